@@ -0,0 +1,287 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Node is a node of the abstract syntax tree of an expression.
+type Node interface {
+	// String returns the node as expression in infix notation.
+	String() string
+}
+
+// NumberLit is a numeric literal.
+type NumberLit struct {
+	Pos   Position
+	Value float64
+	// Text is the literal as written in the expression, but always in decimal
+	// notation and without digit separators or imaginary suffix. The special
+	// floats INF and NAN are Inf and NaN.
+	// It is used by the evaluation modes which are more precise than float64
+	// and may be empty, in which case Value is used.
+	Text string
+	// Imag marks an imaginary literal such as 2i, whose value is Value*i.
+	// Only EvalComplex supports them.
+	Imag bool
+}
+
+// ConstRef references a named constant.
+type ConstRef struct {
+	Pos  Position
+	Name string
+}
+
+// BinaryOp applies the operator Op to the operands X and Y.
+// Pos is the position of the operator.
+type BinaryOp struct {
+	Pos  Position
+	Op   string
+	X, Y Node
+}
+
+// UnaryOp applies the operator Op to the operand X.
+// Pos is the position of the operator.
+type UnaryOp struct {
+	Pos Position
+	Op  string
+	X   Node
+}
+
+// Cond evaluates to Then if Cond is true, otherwise to Else.
+// Pos is the position of the "?".
+type Cond struct {
+	Pos              Position
+	Cond, Then, Else Node
+}
+
+// Call calls the function Name with the given arguments.
+// Postfix operators are calls as well, x! is FACT(x).
+type Call struct {
+	Pos  Position
+	Name string
+	Args []Node
+}
+
+func (n *NumberLit) String() string {
+	if n.overflows() {
+		if n.Imag {
+			return n.Text + "i"
+		}
+		return n.Text
+	}
+	if n.Imag {
+		return strconv.FormatFloat(n.Value, 'f', -1, 64) + "i"
+	}
+	return strconv.FormatFloat(n.Value, 'f', -1, 64)
+}
+
+func (n *ConstRef) String() string {
+	return n.Name
+}
+
+func (n *BinaryOp) String() string {
+	return "(" + n.X.String() + " " + n.Op + " " + n.Y.String() + ")"
+}
+
+func (n *UnaryOp) String() string {
+	return "(" + n.Op + n.X.String() + ")"
+}
+
+func (n *Cond) String() string {
+	return "(" + n.Cond.String() + " ? " + n.Then.String() + " : " + n.Else.String() + ")"
+}
+
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// ParseExpr parses an expression and returns its abstract syntax tree.
+func ParseExpr(s string) (Node, error) {
+	return ParseExprOptions(s, ParseOptions{})
+}
+
+// ParseExprOptions parses an expression like ParseExpr using the configuration opts.
+func ParseExprOptions(s string, opts ParseOptions) (Node, error) {
+	return parse(NewParserOptions(strings.NewReader(s), opts))
+}
+
+// parse parses all tokens of p into an abstract syntax tree.
+func parse(p *Parser) (Node, error) {
+	stack, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err = ShuntingYard(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTree(stack)
+}
+
+// buildTree converts tokens in postfix notation into an abstract syntax tree.
+func buildTree(postfix Stack) (Node, error) {
+	if _, err := validatePostfix(postfix); err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	for _, v := range postfix {
+		switch v.Type {
+		case Number:
+			text := strings.TrimRight(v.Value, "iI")
+			value, normalized, ok := parseNumber(text)
+			if !ok {
+				return nil, &SyntaxError{Kind: InvalidNumber, Pos: v.Pos, Token: v, Msg: fmt.Sprintf("invalid number %s", v.Value)}
+			}
+			nodes = append(nodes, &NumberLit{Pos: v.Pos, Value: value, Text: normalized, Imag: text != v.Value})
+		case Constant:
+			nodes = append(nodes, &ConstRef{Pos: v.Pos, Name: v.Value})
+		case Function:
+			args := make([]Node, v.Args)
+			copy(args, nodes[len(nodes)-v.Args:])
+			nodes = append(nodes[:len(nodes)-v.Args], &Call{Pos: v.Pos, Name: v.Value, Args: args})
+		case PostfixOperator:
+			nodes[len(nodes)-1] = &Call{Pos: v.Pos, Name: postfixFuncs[v.Value], Args: []Node{nodes[len(nodes)-1]}}
+		case UnaryOperator:
+			nodes[len(nodes)-1] = &UnaryOp{Pos: v.Pos, Op: v.Value, X: nodes[len(nodes)-1]}
+		case Operator:
+			if v.Value == "?:" {
+				c, a, b := nodes[len(nodes)-3], nodes[len(nodes)-2], nodes[len(nodes)-1]
+				nodes = append(nodes[:len(nodes)-3], &Cond{Pos: v.Pos, Cond: c, Then: a, Else: b})
+				continue
+			}
+
+			x, y := nodes[len(nodes)-2], nodes[len(nodes)-1]
+			nodes = append(nodes[:len(nodes)-2], &BinaryOp{Pos: v.Pos, Op: v.Value, X: x, Y: y})
+		}
+	}
+
+	return nodes[0], nil
+}
+
+// validatePostfix checks that all operators and functions in the postfix
+// notation have enough operands and that exactly one value is left at the end.
+// It returns the depth of the abstract syntax tree built from the tokens.
+func validatePostfix(postfix Stack) (int, error) {
+	type operand struct {
+		// first is the first token of the operand in the input.
+		first Token
+		depth int
+	}
+
+	var operands []operand
+	for _, v := range postfix {
+		var n int
+		switch v.Type {
+		case Number, Constant:
+			operands = append(operands, operand{first: v, depth: 1})
+			continue
+		case Function:
+			n = v.Args
+		case PostfixOperator, UnaryOperator:
+			n = 1
+		case Operator:
+			switch v.Value {
+			case "?":
+				return 0, &SyntaxError{Kind: UnexpectedToken, Pos: v.Pos, Token: v, Msg: "'?' without matching ':'"}
+			case "?:":
+				n = 3
+			default:
+				n = 2
+			}
+		default:
+			return 0, &SyntaxError{Kind: UnexpectedToken, Pos: v.Pos, Token: v, Msg: fmt.Sprintf("unexpected token %s", v.Value)}
+		}
+
+		if len(operands) < n {
+			switch {
+			case v.Type == Function:
+				return 0, &SyntaxError{Kind: MissingOperand, Pos: v.Pos, Token: v, Msg: fmt.Sprintf("missing argument for function %s", v.Value)}
+			case v.Value == "?:":
+				return 0, &SyntaxError{Kind: MissingOperand, Pos: v.Pos, Token: v, Msg: "missing operand for conditional operator"}
+			}
+			return 0, &SyntaxError{Kind: MissingOperand, Pos: v.Pos, Token: v, Msg: fmt.Sprintf("missing operand for '%s'", v.Value)}
+		}
+
+		// Functions and prefix operators are in front of their operands.
+		res := operand{first: v, depth: 1}
+		if n > 0 && v.Type != Function && v.Type != UnaryOperator {
+			res.first = operands[len(operands)-n].first
+		}
+		for _, arg := range operands[len(operands)-n:] {
+			if arg.depth+1 > res.depth {
+				res.depth = arg.depth + 1
+			}
+		}
+		operands = append(operands[:len(operands)-n], res)
+	}
+
+	switch {
+	case len(operands) == 0:
+		return 0, &SyntaxError{Kind: EmptyExpression, Msg: "empty expression - calculation could not be solved"}
+	case len(operands) > 1:
+		return 0, &SyntaxError{Kind: UnexpectedToken, Pos: operands[1].first.Pos, Token: operands[1].first, Msg: fmt.Sprintf("unexpected value '%s'", operands[1].first.Value)}
+	}
+	return operands[0].depth, nil
+}
+
+// parseNumber parses a number literal, which may have a base prefix
+// (0x, 0b or 0o) or use "_" to separate digits.
+// It returns the value and the literal in decimal notation, or Inf and NaN
+// for the special floats. The value of a literal which is too large for a
+// float64 is infinite, the evaluation modes with more precision use the
+// literal instead.
+func parseNumber(text string) (value float64, normalized string, ok bool) {
+	if len(text) > 2 && text[0] == '0' && strings.ContainsRune("xXbBoO", rune(text[1])) {
+		// Base 0 also checks the placement of underscores.
+		i, ok := new(big.Int).SetString(text, 0)
+		if !ok {
+			return 0, "", false
+		}
+		value, _ = new(big.Float).SetInt(i).Float64()
+		return value, i.String(), true
+	}
+
+	for i, ch := range text {
+		if ch == '_' && (i == 0 || i == len(text)-1 || !isASCIIDigit(rune(text[i-1])) || !isASCIIDigit(rune(text[i+1]))) {
+			return 0, "", false
+		}
+	}
+	normalized = strings.ReplaceAll(text, "_", "")
+
+	value, err := strconv.ParseFloat(normalized, 64)
+	switch {
+	case errors.Is(err, strconv.ErrRange):
+	case err != nil:
+		return 0, "", false
+	case math.IsInf(value, 0):
+		normalized = "Inf"
+	case math.IsNaN(value):
+		normalized = "NaN"
+	}
+	return value, normalized, true
+}
+
+// overflows reports whether the literal is finite but too large for a float64.
+func (n *NumberLit) overflows() bool {
+	return math.IsInf(n.Value, 0) && n.Text != "" && n.Text != "Inf"
+}
+
+func literalOverflow(n *NumberLit, mode string) error {
+	return &EvalError{Kind: Overflow, Pos: n.Pos, Msg: fmt.Sprintf("number %s is too large for %s", n.Text, mode)}
+}
+
+func isASCIIDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,204 @@
+package calc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+// pos returns the position of a column in the first line.
+func pos(column int) calc.Position {
+	return calc.Position{Offset: column - 1, Line: 1, Column: column}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    calc.Node
+		wantErr bool
+	}{
+		{name: "no input", input: "", wantErr: true},
+		{name: "a number", input: "42", want: &calc.NumberLit{Pos: pos(1), Value: 42, Text: "42"}},
+		{name: "a negative number", input: "-42", want: &calc.UnaryOp{Pos: pos(1), Op: "-", X: &calc.NumberLit{Pos: pos(2), Value: 42, Text: "42"}}},
+		{name: "scientific notation", input: "1_0.5e-3", want: &calc.NumberLit{Pos: pos(1), Value: 0.0105, Text: "10.5e-3"}},
+		{name: "a constant", input: " PI", want: &calc.ConstRef{Pos: pos(2), Name: "PI"}},
+		{
+			name:  "operator precedence",
+			input: "1 + 2*3",
+			want: &calc.BinaryOp{
+				Pos: pos(3),
+				Op:  "+",
+				X:   &calc.NumberLit{Pos: pos(1), Value: 1, Text: "1"},
+				Y: &calc.BinaryOp{
+					Pos: pos(6),
+					Op:  "*",
+					X:   &calc.NumberLit{Pos: pos(5), Value: 2, Text: "2"},
+					Y:   &calc.NumberLit{Pos: pos(7), Value: 3, Text: "3"},
+				},
+			},
+		},
+		{
+			name:  "right associative exp",
+			input: "2^3^4",
+			want: &calc.BinaryOp{
+				Pos: pos(2),
+				Op:  "^",
+				X:   &calc.NumberLit{Pos: pos(1), Value: 2, Text: "2"},
+				Y: &calc.BinaryOp{
+					Pos: pos(4),
+					Op:  "^",
+					X:   &calc.NumberLit{Pos: pos(3), Value: 3, Text: "3"},
+					Y:   &calc.NumberLit{Pos: pos(5), Value: 4, Text: "4"},
+				},
+			},
+		},
+		{
+			name:  "function with calculation",
+			input: "COS(3+PI)",
+			want: &calc.Call{Pos: pos(1), Name: "COS", Args: []calc.Node{
+				&calc.BinaryOp{
+					Pos: pos(6),
+					Op:  "+",
+					X:   &calc.NumberLit{Pos: pos(5), Value: 3, Text: "3"},
+					Y:   &calc.ConstRef{Pos: pos(7), Name: "PI"},
+				},
+			}},
+		},
+		{
+			name:  "conditional operator",
+			input: "x>0 ? 1 : 2",
+			want: &calc.Cond{
+				Pos:  pos(5),
+				Cond: &calc.BinaryOp{Pos: pos(2), Op: ">", X: &calc.ConstRef{Pos: pos(1), Name: "X"}, Y: &calc.NumberLit{Pos: pos(3), Value: 0, Text: "0"}},
+				Then: &calc.NumberLit{Pos: pos(7), Value: 1, Text: "1"},
+				Else: &calc.NumberLit{Pos: pos(11), Value: 2, Text: "2"},
+			},
+		},
+		{name: "missing operand", input: "1+", wantErr: true},
+		{name: "conditional operator without colon", input: "1 ? 2", wantErr: true},
+		{name: "colon without conditional operator", input: "1 : 2", wantErr: true},
+		{name: "colon inside parentheses", input: "1 ? (2 : 3)", wantErr: true},
+		{name: "wrong parentheses", input: "(1+2", wantErr: true},
+		{name: "invalid calculation inside a function", input: "COS(3+)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.ParseExpr(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExpr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpr() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprOptions(t *testing.T) {
+	tree, err := calc.ParseExprOptions("-inf < 0 && nan != nan", calc.ParseOptions{Scan: calc.ScanOptions{SpecialFloats: true}})
+	if err != nil {
+		t.Fatalf("ParseExprOptions() error = %v", err)
+	}
+
+	got, err := calc.Eval(tree)
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Eval() got = %v, want 1", got)
+	}
+}
+
+func TestParseExprOptions_ImplicitMul(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "number and constant", input: "2PI", want: "(2 * PI)"},
+		{name: "number and parentheses", input: "3(4+5)", want: "(3 * (4 + 5))"},
+		{name: "two parentheses", input: "(1+2)(3+4)", want: "((1 + 2) * (3 + 4))"},
+		{name: "number and function", input: "2SIN(x)", want: "(2 * SIN(X))"},
+		{name: "same precedence as multiplication", input: "1/2x", want: "((1 / 2) * X)"},
+		{name: "weaker than exp", input: "2x^2", want: "(2 * (X ^ 2))"},
+		{name: "with whitespace", input: "x y", want: "(X * Y)"},
+		{name: "constant and parentheses with whitespace", input: "PI (2)", want: "(PI * 2)"},
+		{name: "variable and parentheses with whitespace", input: "2x (3)", want: "((2 * X) * 3)"},
+		{name: "function with whitespace", input: "2 SIN (x)", want: "(2 * SIN(X))"},
+		{name: "two numbers", input: "2 3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.ParseExprOptions(tt.input, calc.ParseOptions{ImplicitMul: true})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExprOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseExprOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprOptions_CaseSensitive(t *testing.T) {
+	tree, err := calc.ParseExprOptions("dT*10 + dt + round(pi)", calc.ParseOptions{Scan: calc.ScanOptions{CaseSensitive: true}})
+	if err != nil {
+		t.Fatalf("ParseExprOptions() error = %v", err)
+	}
+
+	got, err := calc.EvalWith(tree, calc.Env{"dT": 2, "dt": 1})
+	if err != nil {
+		t.Fatalf("EvalWith() error = %v", err)
+	}
+	if got != 24 {
+		t.Errorf("EvalWith() got = %v, want 24", got)
+	}
+
+	_, err = calc.EvalWith(tree, calc.Env{"DT": 2, "dt": 1})
+	if want := "1:1: undefined variable dT, did you mean DT or dt?"; err == nil || err.Error() != want {
+		t.Errorf("EvalWith() error = %v, want %v", err, want)
+	}
+}
+
+func TestNode_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		input calc.Node
+		want  string
+	}{
+		{name: "a number", input: &calc.NumberLit{Value: 4.5}, want: "4.5"},
+		{name: "a constant", input: &calc.ConstRef{Name: "PI"}, want: "PI"},
+		{name: "unary operator", input: &calc.UnaryOp{Op: "-", X: &calc.ConstRef{Name: "E"}}, want: "(-E)"},
+		{
+			name: "nested operators",
+			input: &calc.BinaryOp{
+				Op: "*",
+				X:  &calc.BinaryOp{Op: "+", X: &calc.NumberLit{Value: 1}, Y: &calc.NumberLit{Value: 2}},
+				Y:  &calc.NumberLit{Value: 3},
+			},
+			want: "((1 + 2) * 3)",
+		},
+		{
+			name:  "function call",
+			input: &calc.Call{Name: "SQRT", Args: []calc.Node{&calc.NumberLit{Value: 16}}},
+			want:  "SQRT(16)",
+		},
+		{
+			name:  "conditional",
+			input: &calc.Cond{Cond: &calc.ConstRef{Name: "X"}, Then: &calc.NumberLit{Value: 1}, Else: &calc.NumberLit{Value: 2}},
+			want:  "(X ? 1 : 2)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultPrec is the mantissa precision in bits used by SolveBig and EvalBig
+// if no precision is given.
+const DefaultPrec uint = 256
+
+type bigFunc struct {
+	arity
+	fn func(prec uint, args []*big.Float) (*big.Float, error)
+}
+
+var bigConsts = map[string]func(prec uint) *big.Float{
+	"E":       bigE,
+	"PI":      bigPi,
+	"PHI":     bigPhi,
+	"SQRT2":   func(prec uint) *big.Float { return bigSqrt(bigInt64(2, prec), prec) },
+	"SQRTE":   func(prec uint) *big.Float { return bigSqrt(bigE(prec), prec) },
+	"SQRTPI":  func(prec uint) *big.Float { return bigSqrt(bigPi(prec), prec) },
+	"SQRTPHI": func(prec uint) *big.Float { return bigSqrt(bigPhi(prec), prec) },
+	"LN2":     func(prec uint) *big.Float { return bigLog(bigInt64(2, prec), prec) },
+	"LN10":    func(prec uint) *big.Float { return bigLog(bigInt64(10, prec), prec) },
+	"LOG2E": func(prec uint) *big.Float {
+		return newBig(prec).Quo(bigInt64(1, prec), bigLog(bigInt64(2, prec), prec))
+	},
+	"LOG10E": func(prec uint) *big.Float {
+		return newBig(prec).Quo(bigInt64(1, prec), bigLog(bigInt64(10, prec), prec))
+	},
+}
+
+var bigFuncs = map[string]bigFunc{
+	"FACT": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if !args[0].IsInt() {
+			return nil, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("FACT of %s is not supported with big.Float, it needs an integer", bigText(args[0]))}
+		}
+		n, _ := args[0].Int(nil)
+		res, err := intFactorial(n)
+		if err != nil {
+			return nil, err
+		}
+		return newBig(prec).SetInt(res), nil
+	}},
+	"ABS": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		return newBig(prec).Abs(args[0]), nil
+	}},
+	"SQRT": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if args[0].Sign() < 0 {
+			return nil, bigDomainError("SQRT", args)
+		}
+		return bigSqrt(args[0], prec), nil
+	}},
+	"CBRT": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if args[0].Sign() == 0 {
+			return newBig(prec), nil
+		}
+		l := bigLog(newBig(prec).Abs(args[0]), prec)
+		res := bigExp(l.Quo(l, bigInt64(3, prec)), prec)
+		if args[0].Sign() < 0 {
+			res.Neg(res)
+		}
+		return res, nil
+	}},
+	"CEIL": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		return bigCeil(args[0]), nil
+	}},
+	"FLOOR": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		return bigFloor(args[0]), nil
+	}},
+	"LN": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if args[0].Sign() <= 0 {
+			return nil, bigDomainError("LN", args)
+		}
+		return bigLog(args[0], prec), nil
+	}},
+	"SIN": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if err := checkSinCosArg("SIN", args[0]); err != nil {
+			return nil, err
+		}
+		sin, _ := bigSinCos(args[0], prec)
+		return sin, nil
+	}},
+	"COS": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if err := checkSinCosArg("COS", args[0]); err != nil {
+			return nil, err
+		}
+		_, cos := bigSinCos(args[0], prec)
+		return cos, nil
+	}},
+	"TAN": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if err := checkSinCosArg("TAN", args[0]); err != nil {
+			return nil, err
+		}
+		sin, cos := bigSinCos(args[0], prec)
+		return sin.Quo(sin, cos), nil
+	}},
+	"ASIN": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if new(big.Float).Abs(args[0]).Cmp(big.NewFloat(1)) > 0 {
+			return nil, bigDomainError("ASIN", args)
+		}
+		return bigAsin(args[0], prec), nil
+	}},
+	"ACOS": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if new(big.Float).Abs(args[0]).Cmp(big.NewFloat(1)) > 0 {
+			return nil, bigDomainError("ACOS", args)
+		}
+		res := bigPi(prec)
+		res.Quo(res, bigInt64(2, prec))
+		return res.Sub(res, bigAsin(args[0], prec)), nil
+	}},
+	"ATAN": {arity{1, 1}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		return bigAtan(args[0], prec), nil
+	}},
+	"ATAN2": {arity{2, 2}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		y, x := args[0], args[1]
+		if x.Sign() == 0 {
+			res := bigPi(prec)
+			res.Quo(res, bigInt64(2, prec))
+			return res.Mul(res, bigInt64(int64(y.Sign()), prec)), nil
+		}
+
+		res := bigAtan(newBig(prec).Quo(y, x), prec)
+		if x.Sign() < 0 {
+			if y.Sign() < 0 {
+				res.Sub(res, bigPi(prec))
+			} else {
+				res.Add(res, bigPi(prec))
+			}
+		}
+		return res, nil
+	}},
+	"MIN": {arity{1, Variadic}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) < 0 {
+				res = v
+			}
+		}
+		return newBig(prec).Set(res), nil
+	}},
+	"MAX": {arity{1, Variadic}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) > 0 {
+				res = v
+			}
+		}
+		return newBig(prec).Set(res), nil
+	}},
+	"SUM": {arity{1, Variadic}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		return bigSum(args, prec), nil
+	}},
+	"AVG": {arity{1, Variadic}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		res := bigSum(args, prec)
+		return res.Quo(res, bigInt64(int64(len(args)), prec)), nil
+	}},
+	"POW": {arity{2, 2}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		res, ok := bigPow(args[0], args[1], prec)
+		if !ok {
+			return nil, bigDomainError("POW", args)
+		}
+		return res, nil
+	}},
+	"LOG": {arity{1, 2}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		base := bigInt64(10, prec)
+		if len(args) == 2 {
+			base = args[1]
+		}
+		if args[0].Sign() <= 0 || base.Sign() <= 0 || base.Cmp(big.NewFloat(1)) == 0 {
+			return nil, bigDomainError("LOG", args)
+		}
+		res := bigLog(args[0], prec)
+		return res.Quo(res, bigLog(base, prec)), nil
+	}},
+	"HYPOT": {arity{2, 2}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		x := newBig(prec).Mul(args[0], args[0])
+		y := newBig(prec).Mul(args[1], args[1])
+		return bigSqrt(x.Add(x, y), prec), nil
+	}},
+	"ROUND": {arity{1, 2}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if len(args) == 1 {
+			return roundHalfAway(args[0]), nil
+		}
+
+		digits, acc := args[1].Int64()
+		if acc != big.Exact {
+			return nil, &EvalError{Kind: FunctionError, Msg: "ROUND", Err: fmt.Errorf("digits must be an integer, got %v", args[1])}
+		}
+		scale := bigPowInt(bigInt64(10, prec), digits, prec)
+		res := newBig(prec).Mul(args[0], scale)
+		res = roundHalfAway(res)
+		return res.Quo(res, scale), nil
+	}},
+	"CLAMP": {arity{3, 3}, func(prec uint, args []*big.Float) (*big.Float, error) {
+		if args[1].Cmp(args[2]) > 0 {
+			return nil, &EvalError{Kind: FunctionError, Msg: "CLAMP", Err: fmt.Errorf("lower bound %v is greater than upper bound %v", args[1], args[2])}
+		}
+		res := args[0]
+		if res.Cmp(args[1]) < 0 {
+			res = args[1]
+		} else if res.Cmp(args[2]) > 0 {
+			res = args[2]
+		}
+		return newBig(prec).Set(res), nil
+	}},
+}
+
+// SolveBig solves a mathematical calculation using big.Float values with a
+// mantissa precision of prec bits. A prec of 0 uses DefaultPrec.
+func SolveBig(s string, prec uint) (*big.Float, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return EvalBig(tree, nil, prec)
+}
+
+// EvalBig evaluates the abstract syntax tree of an expression using big.Float
+// values with a mantissa precision of prec bits. A prec of 0 uses DefaultPrec.
+// Intermediate results use some more bits, so that the result is correctly
+// rounded in most cases.
+//
+// All built-in constants are computed at that precision, but only the built-in
+// functions are available and FACT only for integers. Functions registered by
+// the user work on float64 and are therefore not supported.
+func EvalBig(n Node, vars Env, prec uint) (res *big.Float, err error) {
+	if prec == 0 {
+		prec = DefaultPrec
+	}
+
+	// big.Float panics on operations like Inf - Inf, which can only
+	// happen with infinite variables.
+	defer func() {
+		if r := recover(); r != nil {
+			nan, ok := r.(big.ErrNaN)
+			if !ok {
+				panic(r)
+			}
+			res, err = nil, &EvalError{Kind: DomainError, Msg: nan.Error()}
+		}
+	}()
+
+	e := bigEvaluator{vars: vars, prec: prec + guardBits}
+	res, err = e.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	return res.SetPrec(prec), nil
+}
+
+type bigEvaluator struct {
+	vars Env
+	prec uint
+}
+
+func (e bigEvaluator) eval(n Node) (*big.Float, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return nil, imaginaryUnsupported(n, "big.Float")
+		}
+		if math.IsNaN(n.Value) {
+			return nil, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: "NaN is not supported with big.Float"}
+		}
+		text := n.Text
+		if text == "" {
+			text = strconv.FormatFloat(n.Value, 'g', -1, 64)
+		}
+		res, _, err := big.ParseFloat(text, 0, e.prec, big.ToNearestEven)
+		if err != nil {
+			return nil, &EvalError{Kind: InvalidNumber, Pos: n.Pos, Msg: fmt.Sprintf("invalid number %s", text)}
+		}
+		return res, nil
+	case *ConstRef:
+		if val, ok := e.vars[n.Name]; ok {
+			if math.IsNaN(val) {
+				return nil, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: fmt.Sprintf("variable %s is NaN", n.Name)}
+			}
+			return newBig(e.prec).SetFloat64(val), nil
+		}
+		if c, ok := bigConsts[strings.ToUpper(n.Name)]; ok {
+			return c(e.prec), nil
+		}
+		_, err := e.vars.Lookup(n.Name)
+		return nil, at(err, n.Pos)
+	case *UnaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			return x.Neg(x), nil
+		}
+		return nil, at(unsupportedOperator(n.Op, "big.Float"), n.Pos)
+	case *BinaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := e.eval(n.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := e.binary(n.Op, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		return nil, at(unsupportedOperator("?:", "big.Float"), n.Pos)
+	case *Call:
+		f, ok := bigFuncs[n.Name]
+		if !ok {
+			return nil, at(unsupportedFunction(n.Name, "big.Float"), n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return nil, at(err, n.Pos)
+		}
+
+		args := make([]*big.Float, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+
+		res, err := f.fn(e.prec, args)
+		return res, at(err, n.Pos)
+	}
+
+	return nil, fmt.Errorf("unsupported node %T", n)
+}
+
+func (e bigEvaluator) binary(op string, x, y *big.Float) (*big.Float, error) {
+	res := newBig(e.prec)
+	switch op {
+	case "+":
+		return res.Add(x, y), nil
+	case "-":
+		return res.Sub(x, y), nil
+	case "*":
+		return res.Mul(x, y), nil
+	case "/":
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s / %s", bigText(x), bigText(y))}
+		}
+		return res.Quo(x, y), nil
+	case "//", "%":
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s %s %s", bigText(x), op, bigText(y))}
+		}
+
+		quo := bigFloor(res.Quo(x, y))
+		if op == "//" {
+			return quo, nil
+		}
+		return res.Sub(x, quo.Mul(quo, y)), nil
+	case "^":
+		res, ok := bigPow(x, y, e.prec)
+		if !ok {
+			return nil, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%s ^ %s is not defined", bigText(x), bigText(y))}
+		}
+		return res, nil
+	}
+	return nil, unsupportedOperator(op, "big.Float")
+}
+
+// unsupportedFunction returns an error for a function which either does not
+// exist at all or is not available in the given evaluation mode.
+func unsupportedFunction(name, mode string) error {
+	if _, err := DefaultRegistry.lookup(name); err != nil {
+		return err
+	}
+	return &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("function %s is not supported with %s", name, mode)}
+}
+
+// checkSinCosArg returns an Overflow error if x is too large for bigSinCos.
+func checkSinCosArg(name string, x *big.Float) error {
+	if x.MantExp(nil) > maxSinCosExp {
+		return &EvalError{Kind: Overflow, Msg: fmt.Sprintf("argument of %s is too large, it must be below 2^%d", name, maxSinCosExp)}
+	}
+	return nil
+}
+
+func bigDomainError(name string, args []*big.Float) error {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		texts[i] = bigText(arg)
+	}
+	return &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%s is not defined for [%s]", name, strings.Join(texts, " "))}
+}
+
+func bigText(x *big.Float) string {
+	return x.Text('g', 10)
+}
+
+func bigSum(values []*big.Float, prec uint) *big.Float {
+	res := newBig(prec)
+	for _, v := range values {
+		res.Add(res, v)
+	}
+	return res
+}
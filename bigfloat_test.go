@@ -0,0 +1,139 @@
+package calc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestSolveBig(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		prec     uint
+		want     string
+		wantKind calc.ErrorKind
+	}{
+		{name: "exact decimal sum", input: "0.1+0.2", want: "0.3"},
+		{name: "pi", input: "PI", want: "3.141592653589793238462643383279502884197"},
+		{name: "e", input: "E", want: "2.718281828459045235360287471352662497757"},
+		{name: "golden ratio", input: "PHI", want: "1.61803398874989484820458683436563811772"},
+		{name: "ln 10", input: "LN10", want: "2.302585092994045684017991454684364207601"},
+		{name: "log10 e", input: "LOG10E", want: "0.4342944819032518276511289189166050822944"},
+		{name: "sqrt", input: "SQRT(2)", want: "1.41421356237309504880168872420969807857"},
+		{name: "fractional power", input: "2^0.5", want: "1.41421356237309504880168872420969807857"},
+		{name: "integer power", input: "3^-2", want: "0.1111111111111111111111111111111111111111"},
+		{name: "literal beyond float64", input: "1e400 / 4e399", want: "2.5"},
+		{name: "huge integer power", input: "2^200", want: "1.606938044258990275541962092341162602522e+60"},
+		{name: "sin", input: "SIN(1)", want: "0.8414709848078965066525023216302989996226"},
+		{name: "cos of a large argument", input: "COS(1000000)", want: "0.9367521275331447869385325350749187757081"},
+		{name: "sin of a huge argument", input: "SIN(2^100000)", wantKind: calc.Overflow},
+		{name: "cos of the largest argument", input: "COS(2^16383)", prec: 64, want: "0.9210843909921906207116659714984052698128"},
+		{name: "tan", input: "TAN(1)", want: "1.557407724654902230506974807458360173087"},
+		{name: "asin", input: "ASIN(1)", want: "1.570796326794896619231321691639751442099"},
+		{name: "acos", input: "ACOS(0.5)", want: "1.047197551196597746154214461093167628066"},
+		{name: "atan", input: "ATAN(100)", want: "1.560796660108231381024981575430471893537"},
+		{name: "atan2", input: "ATAN2(-1, -1)", want: "-2.356194490192344928846982537459627163148"},
+		{name: "cbrt", input: "CBRT(-27)", want: "-3"},
+		{name: "log with base", input: "LOG(8, 2)", want: "3"},
+		{name: "round with digits", input: "ROUND(2.675, 2)", want: "2.68"},
+		{name: "floor and ceil", input: "FLOOR(-2.5) + CEIL(2.1)", want: "0"},
+		{name: "variadic function", input: "AVG(1, 2, MAX(3, 4))", want: "2.333333333333333333333333333333333333333"},
+		{name: "low precision", input: "1/3", prec: 8, want: "0.333984375"},
+		{name: "division by zero", input: "1/(2-2)", wantKind: calc.DivisionByZero},
+		{name: "floor division", input: "-7.5 // 2", want: "-4"},
+		{name: "modulo", input: "-7.5 % 2", want: "0.5"},
+		{name: "factorial", input: "30!", want: "265252859812191058636308480000000"},
+		{name: "factorial of fraction", input: "0.5!", wantKind: calc.Unsupported},
+		{name: "domain error", input: "LN(-1)", wantKind: calc.DomainError},
+		{name: "negative base with fractional exponent", input: "(-8)^(1/3)", wantKind: calc.DomainError},
+		{name: "unknown function", input: "LOOL(1)", wantKind: calc.UnknownFunction},
+		{name: "unknown identifier", input: "LOOL", wantKind: calc.UnknownIdentifier},
+		{name: "wrong argument count", input: "HYPOT(1)", wantKind: calc.ArgumentCount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prec := tt.prec
+			if prec == 0 {
+				prec = 200
+			}
+
+			got, err := calc.SolveBig(tt.input, prec)
+			if tt.wantKind != 0 {
+				var evalErr *calc.EvalError
+				if !errors.As(err, &evalErr) || evalErr.Kind != tt.wantKind {
+					t.Errorf("SolveBig() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("SolveBig() error = %v", err)
+			}
+
+			if got.Prec() != prec {
+				t.Errorf("SolveBig() precision = %v, want %v", got.Prec(), prec)
+			}
+			if text := got.Text('g', 40); text != tt.want {
+				t.Errorf("SolveBig() got = %v, want %v", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalBig(t *testing.T) {
+	tree, err := calc.ParseExpr("x*PI")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calc.EvalBig(tree, calc.Env{"X": 2}, 0)
+	if err != nil {
+		t.Fatalf("EvalBig() error = %v", err)
+	}
+	if got.Prec() != calc.DefaultPrec {
+		t.Errorf("EvalBig() precision = %v, want %v", got.Prec(), calc.DefaultPrec)
+	}
+	if want := "6.283185307179586476925286766559005768394"; got.Text('g', 40) != want {
+		t.Errorf("EvalBig() got = %v, want %v", got.Text('g', 40), want)
+	}
+
+	// Only the built-in functions have a big.Float version.
+	r := calc.NewRegistry()
+	if err := r.Register("BIGTESTFUNC", 0, 0, func(args ...float64) (float64, error) {
+		return 1, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tree, err = calc.ParseExpr("BIGTESTFUNC()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evalErr *calc.EvalError
+	if _, err := calc.EvalBig(tree, nil, 0); !errors.As(err, &evalErr) || evalErr.Kind != calc.UnknownFunction {
+		t.Errorf("EvalBig() error = %v, want %v", err, calc.UnknownFunction)
+	}
+}
+
+func TestEvalBig_SpecialFloats(t *testing.T) {
+	opts := calc.ParseOptions{Scan: calc.ScanOptions{SpecialFloats: true}}
+	tree, err := calc.ParseExprOptions("-inf * 2", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := calc.EvalBig(tree, nil, 0)
+	if err != nil {
+		t.Fatalf("EvalBig() error = %v", err)
+	}
+	if !got.IsInf() || got.Sign() > 0 {
+		t.Errorf("EvalBig() got = %v, want -Inf", got)
+	}
+
+	tree, err = calc.ParseExprOptions("nan + 1", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evalErr *calc.EvalError
+	if _, err := calc.EvalBig(tree, nil, 0); !errors.As(err, &evalErr) || evalErr.Kind != calc.DomainError {
+		t.Errorf("EvalBig() error = %v, want %v", err, calc.DomainError)
+	}
+}
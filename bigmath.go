@@ -0,0 +1,358 @@
+package calc
+
+import (
+	"math"
+	"math/big"
+)
+
+// guardBits are added to the precision of intermediate results of the
+// big.Float functions so that the final result is correctly rounded in most cases.
+const guardBits = 32
+
+func newBig(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func bigInt64(v int64, prec uint) *big.Float {
+	return newBig(prec).SetInt64(v)
+}
+
+// converged reports whether term is too small to change sum at the given precision.
+func converged(term, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return false
+	}
+	return term.MantExp(nil) < sum.MantExp(nil)-int(prec)-1
+}
+
+// bigPi returns pi using Machin's formula pi = 16*atan(1/5) - 4*atan(1/239).
+func bigPi(prec uint) *big.Float {
+	wp := prec + guardBits
+	a := atanInv(5, wp)
+	a.Mul(a, bigInt64(16, wp))
+	b := atanInv(239, wp)
+	b.Mul(b, bigInt64(4, wp))
+	return a.Sub(a, b).SetPrec(prec)
+}
+
+// bigE returns Euler's number.
+func bigE(prec uint) *big.Float {
+	return bigExp(bigInt64(1, prec), prec)
+}
+
+// bigPhi returns the golden ratio (1 + sqrt(5)) / 2.
+func bigPhi(prec uint) *big.Float {
+	phi := bigSqrt(bigInt64(5, prec), prec)
+	phi.Add(phi, bigInt64(1, prec))
+	return phi.Quo(phi, bigInt64(2, prec))
+}
+
+// atanInv returns atan(1/n) using its Taylor series.
+func atanInv(n int64, prec uint) *big.Float {
+	nn := bigInt64(n*n, prec)
+	power := newBig(prec).Quo(bigInt64(1, prec), bigInt64(n, prec))
+	sum := newBig(prec).Set(power)
+	term := newBig(prec)
+	for k := int64(1); ; k++ {
+		power.Quo(power, nn)
+		term.Quo(power, bigInt64(2*k+1, prec))
+		if converged(term, sum, prec) {
+			return sum
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+}
+
+// bigExp returns e^x.
+func bigExp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return bigInt64(1, prec)
+	}
+
+	// Reduce x below 2^-8 and square the result afterwards.
+	// Every squaring loses a bit, so the working precision is increased accordingly.
+	halvings := 0
+	if exp := x.MantExp(nil); exp > -8 {
+		halvings = exp + 8
+	}
+	wp := prec + guardBits + uint(halvings)
+	r := newBig(wp).SetMantExp(x, -halvings)
+
+	sum := bigInt64(1, wp)
+	term := bigInt64(1, wp)
+	for i := int64(1); ; i++ {
+		term.Mul(term, r)
+		term.Quo(term, bigInt64(i, wp))
+		if converged(term, sum, wp) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+
+	for i := 0; i < halvings; i++ {
+		sum.Mul(sum, sum)
+	}
+	return sum.SetPrec(prec)
+}
+
+// bigLog returns the natural logarithm of x, which must be positive.
+func bigLog(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+
+	// ln(x) = ln(mant) + exp*ln(2) with mant in [0.5, 1).
+	mant := newBig(wp)
+	exp := x.MantExp(mant)
+	res := logNewton(mant, wp)
+	if exp != 0 {
+		ln2 := logNewton(newBig(wp).SetFloat64(0.5), wp)
+		ln2.Neg(ln2)
+		res.Add(res, ln2.Mul(ln2, bigInt64(int64(exp), wp)))
+	}
+	return res.SetPrec(prec)
+}
+
+// logNewton returns ln(x) for x in [0.5, 1) using Halley's method on exp.
+func logNewton(x *big.Float, prec uint) *big.Float {
+	f, _ := x.Float64()
+	y := newBig(prec).SetFloat64(math.Log(f))
+
+	// The amount of correct bits triples with each iteration.
+	for bits := uint(50); bits < 3*prec; bits *= 3 {
+		ey := bigExp(y, prec)
+		num := newBig(prec).Sub(x, ey)
+		den := newBig(prec).Add(x, ey)
+		num.Quo(num, den)
+		y.Add(y, num.Mul(num, bigInt64(2, prec)))
+	}
+	return y
+}
+
+// bigSqrt returns the square root of x, which must not be negative.
+func bigSqrt(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return newBig(prec)
+	}
+	return newBig(prec).Sqrt(x)
+}
+
+// maxSinCosExp is the largest binary exponent of an argument of bigSinCos.
+// Larger arguments would need too many bits of pi, see bigSinCos.
+const maxSinCosExp = 1 << 14
+
+// bigSinCos returns sin(x) and cos(x).
+// The binary exponent of x must not be larger than maxSinCosExp.
+func bigSinCos(x *big.Float, prec uint) (sin, cos *big.Float) {
+	// Reducing large arguments by 2*pi cancels as many bits as x has
+	// in front of the binary point, so pi needs that many bits more.
+	extra := uint(0)
+	if exp := x.MantExp(nil); exp > 0 {
+		extra = uint(exp)
+	}
+	wp := prec + guardBits + extra
+
+	twoPi := bigPi(wp)
+	twoPi.Mul(twoPi, bigInt64(2, wp))
+	k := newBig(wp).Quo(x, twoPi)
+	kInt, _ := roundHalfAway(k).Int(nil)
+	r := newBig(wp).Mul(newBig(wp).SetInt(kInt), twoPi)
+	r.Sub(newBig(wp).Set(x), r)
+
+	// Taylor series of sin and cos evaluated together.
+	sin, cos = newBig(wp), bigInt64(1, wp)
+	term := bigInt64(1, wp)
+	for i := int64(1); ; i++ {
+		term.Mul(term, r)
+		term.Quo(term, bigInt64(i, wp))
+		if converged(term, sin, wp) && converged(term, cos, wp) {
+			break
+		}
+
+		switch i % 4 {
+		case 0:
+			cos.Add(cos, term)
+		case 1:
+			sin.Add(sin, term)
+		case 2:
+			cos.Sub(cos, term)
+		case 3:
+			sin.Sub(sin, term)
+		}
+	}
+	return sin.SetPrec(prec), cos.SetPrec(prec)
+}
+
+// bigAtan returns atan(x).
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	one := bigInt64(1, wp)
+
+	if x.Sign() == 0 {
+		return newBig(prec)
+	}
+
+	// atan(x) = sign(x)*pi/2 - atan(1/x) for |x| > 1.
+	if new(big.Float).Abs(x).Cmp(one) > 0 {
+		halfPi := bigPi(wp)
+		halfPi.Quo(halfPi, bigInt64(2, wp))
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		inv := newBig(wp).Quo(one, x)
+		return halfPi.Sub(halfPi, bigAtan(inv, wp)).SetPrec(prec)
+	}
+
+	// atan(x) = 2*atan(x / (1 + sqrt(1 + x^2))) until x is small.
+	r := newBig(wp).Set(x)
+	doublings := 0
+	for r.MantExp(nil) > -8 {
+		s := newBig(wp).Mul(r, r)
+		s.Add(s, one)
+		s.Sqrt(s)
+		s.Add(s, one)
+		r.Quo(r, s)
+		doublings++
+	}
+
+	// Taylor series.
+	r2 := newBig(wp).Mul(r, r)
+	power := newBig(wp).Set(r)
+	sum := newBig(wp).Set(r)
+	term := newBig(wp)
+	for k := int64(1); ; k++ {
+		power.Mul(power, r2)
+		term.Quo(power, bigInt64(2*k+1, wp))
+		if converged(term, sum, wp) {
+			break
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+
+	return sum.SetMantExp(sum, doublings).SetPrec(prec)
+}
+
+// bigAsin returns asin(x) for x in [-1, 1].
+func bigAsin(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	one := bigInt64(1, wp)
+
+	// asin(x) = atan(x / sqrt(1 - x^2)) with the poles at +-1 handled separately.
+	if new(big.Float).Abs(x).Cmp(one) == 0 {
+		halfPi := bigPi(wp)
+		halfPi.Quo(halfPi, bigInt64(2, wp))
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi.SetPrec(prec)
+	}
+
+	d := newBig(wp).Mul(x, x)
+	d.Sub(one, d)
+	d.Sqrt(d)
+	return bigAtan(d.Quo(x, d), wp).SetPrec(prec)
+}
+
+// bigPow returns x^y.
+// ok is false if the result is not a real number.
+func bigPow(x, y *big.Float, prec uint) (res *big.Float, ok bool) {
+	wp := prec + guardBits
+
+	if y.IsInt() {
+		if n, acc := y.Int64(); acc == big.Exact {
+			if x.Sign() == 0 && n < 0 {
+				return nil, false
+			}
+			return bigPowInt(x, n, prec), true
+		}
+	}
+
+	switch x.Sign() {
+	case 0:
+		if y.Sign() <= 0 {
+			return nil, false
+		}
+		return newBig(prec), true
+	case -1:
+		return nil, false
+	}
+
+	l := bigLog(x, wp)
+	return bigExp(l.Mul(l, y), wp).SetPrec(prec), true
+}
+
+// bigPowInt returns x^n using binary exponentiation.
+func bigPowInt(x *big.Float, n int64, prec uint) *big.Float {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+
+	// Every multiplication may lose a bit.
+	wp := prec + guardBits + uint(bitLen(n))
+	res := bigInt64(1, wp)
+	base := newBig(wp).Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			res.Mul(res, base)
+		}
+		base.Mul(base, base)
+	}
+
+	if neg {
+		res.Quo(bigInt64(1, wp), res)
+	}
+	return res.SetPrec(prec)
+}
+
+func bitLen(n int64) int {
+	return big.NewInt(n).BitLen()
+}
+
+// roundHalfAway rounds x to the nearest integer, rounding halves away from zero.
+func roundHalfAway(x *big.Float) *big.Float {
+	half := new(big.Float).SetFloat64(0.5)
+	abs := new(big.Float).SetPrec(x.Prec() + 1).Abs(x)
+	abs.Add(abs, half)
+	res := bigTrunc(abs)
+	if x.Sign() < 0 {
+		res.Neg(res)
+	}
+	return res
+}
+
+// bigTrunc rounds x toward zero.
+func bigTrunc(x *big.Float) *big.Float {
+	if x.IsInt() {
+		return new(big.Float).Set(x)
+	}
+	i, _ := x.Int(nil)
+	return new(big.Float).SetPrec(x.Prec()).SetInt(i)
+}
+
+// bigFloor rounds x toward negative infinity.
+func bigFloor(x *big.Float) *big.Float {
+	res := bigTrunc(x)
+	if x.Sign() < 0 && !x.IsInt() {
+		res.Sub(res, big.NewFloat(1))
+	}
+	return res
+}
+
+// bigCeil rounds x toward positive infinity.
+func bigCeil(x *big.Float) *big.Float {
+	res := bigTrunc(x)
+	if x.Sign() > 0 && !x.IsInt() {
+		res.Add(res, big.NewFloat(1))
+	}
+	return res
+}
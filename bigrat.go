@@ -0,0 +1,276 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type ratFunc struct {
+	arity
+	fn func(args []*big.Rat) (*big.Rat, error)
+}
+
+var ratFuncs = map[string]ratFunc{
+	"ABS": {arity{1, 1}, func(args []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Abs(args[0]), nil
+	}},
+	"CEIL": {arity{1, 1}, func(args []*big.Rat) (*big.Rat, error) {
+		return ratCeil(args[0]), nil
+	}},
+	"FLOOR": {arity{1, 1}, func(args []*big.Rat) (*big.Rat, error) {
+		return ratFloor(args[0]), nil
+	}},
+	"MIN": {arity{1, Variadic}, func(args []*big.Rat) (*big.Rat, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) < 0 {
+				res = v
+			}
+		}
+		return new(big.Rat).Set(res), nil
+	}},
+	"MAX": {arity{1, Variadic}, func(args []*big.Rat) (*big.Rat, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) > 0 {
+				res = v
+			}
+		}
+		return new(big.Rat).Set(res), nil
+	}},
+	"SUM": {arity{1, Variadic}, func(args []*big.Rat) (*big.Rat, error) {
+		return ratSum(args), nil
+	}},
+	"AVG": {arity{1, Variadic}, func(args []*big.Rat) (*big.Rat, error) {
+		res := ratSum(args)
+		return res.Quo(res, new(big.Rat).SetInt64(int64(len(args)))), nil
+	}},
+	"POW": {arity{2, 2}, func(args []*big.Rat) (*big.Rat, error) {
+		return ratPow(args[0], args[1])
+	}},
+	"FACT": {arity{1, 1}, func(args []*big.Rat) (*big.Rat, error) {
+		if !args[0].IsInt() {
+			return nil, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("FACT of %s is not supported with big.Rat, it needs an integer", ratText(args[0]))}
+		}
+		res, err := intFactorial(args[0].Num())
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).SetInt(res), nil
+	}},
+	"ROUND": {arity{1, 2}, func(args []*big.Rat) (*big.Rat, error) {
+		if len(args) == 1 {
+			return ratRound(args[0]), nil
+		}
+
+		if !args[1].IsInt() || !args[1].Num().IsInt64() {
+			return nil, &EvalError{Kind: FunctionError, Msg: "ROUND", Err: fmt.Errorf("digits must be an integer, got %s", ratText(args[1]))}
+		}
+		scale, err := ratPow(new(big.Rat).SetInt64(10), args[1])
+		if err != nil {
+			return nil, err
+		}
+		res := ratRound(new(big.Rat).Mul(args[0], scale))
+		return res.Quo(res, scale), nil
+	}},
+	"CLAMP": {arity{3, 3}, func(args []*big.Rat) (*big.Rat, error) {
+		if args[1].Cmp(args[2]) > 0 {
+			return nil, &EvalError{Kind: FunctionError, Msg: "CLAMP", Err: fmt.Errorf("lower bound %s is greater than upper bound %s", ratText(args[1]), ratText(args[2]))}
+		}
+		res := args[0]
+		if res.Cmp(args[1]) < 0 {
+			res = args[1]
+		} else if res.Cmp(args[2]) > 0 {
+			res = args[2]
+		}
+		return new(big.Rat).Set(res), nil
+	}},
+}
+
+// SolveRat solves a mathematical calculation exactly using big.Rat values.
+func SolveRat(s string) (*big.Rat, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return EvalRat(tree, nil)
+}
+
+// EvalRat evaluates the abstract syntax tree of an expression exactly using
+// big.Rat values.
+//
+// Only operations with rational results are supported: "^" needs an integer
+// exponent, the irrational built-in constants are not available and only the
+// built-in functions ABS, CEIL, FLOOR, MIN, MAX, SUM, AVG, POW, FACT, ROUND and
+// CLAMP can be called. Variables are converted exactly from their float64 value.
+func EvalRat(n Node, vars Env) (*big.Rat, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return nil, imaginaryUnsupported(n, "big.Rat")
+		}
+		text := n.Text
+		if text == "" {
+			text = strconv.FormatFloat(n.Value, 'g', -1, 64)
+		}
+		res, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return nil, &EvalError{Kind: InvalidNumber, Pos: n.Pos, Msg: fmt.Sprintf("invalid number %s", text)}
+		}
+		return res, nil
+	case *ConstRef:
+		if val, ok := vars[n.Name]; ok {
+			if math.IsNaN(val) || math.IsInf(val, 0) {
+				return nil, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: fmt.Sprintf("variable %s is not a rational number: %v", n.Name, val)}
+			}
+			return new(big.Rat).SetFloat64(val), nil
+		}
+		if _, ok := consts[strings.ToUpper(n.Name)]; ok {
+			return nil, &EvalError{Kind: Unsupported, Pos: n.Pos, Msg: fmt.Sprintf("constant %s is not supported with big.Rat", n.Name)}
+		}
+		_, err := vars.Lookup(n.Name)
+		return nil, at(err, n.Pos)
+	case *UnaryOp:
+		x, err := EvalRat(n.X, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			return x.Neg(x), nil
+		}
+		return nil, at(unsupportedOperator(n.Op, "big.Rat"), n.Pos)
+	case *BinaryOp:
+		x, err := EvalRat(n.X, vars)
+		if err != nil {
+			return nil, err
+		}
+		y, err := EvalRat(n.Y, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := ratBinary(n.Op, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		return nil, at(unsupportedOperator("?:", "big.Rat"), n.Pos)
+	case *Call:
+		f, ok := ratFuncs[n.Name]
+		if !ok {
+			return nil, at(unsupportedFunction(n.Name, "big.Rat"), n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return nil, at(err, n.Pos)
+		}
+
+		args := make([]*big.Rat, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := EvalRat(arg, vars)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+
+		res, err := f.fn(args)
+		return res, at(err, n.Pos)
+	}
+
+	return nil, fmt.Errorf("unsupported node %T", n)
+}
+
+func ratBinary(op string, x, y *big.Rat) (*big.Rat, error) {
+	res := new(big.Rat)
+	switch op {
+	case "+":
+		return res.Add(x, y), nil
+	case "-":
+		return res.Sub(x, y), nil
+	case "*":
+		return res.Mul(x, y), nil
+	case "/":
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s / %s", ratText(x), ratText(y))}
+		}
+		return res.Quo(x, y), nil
+	case "//", "%":
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s %s %s", ratText(x), op, ratText(y))}
+		}
+
+		quo := ratFloor(res.Quo(x, y))
+		if op == "//" {
+			return quo, nil
+		}
+		return res.Sub(x, quo.Mul(quo, y)), nil
+	case "^":
+		return ratPow(x, y)
+	}
+	return nil, unsupportedOperator(op, "big.Rat")
+}
+
+// ratPow returns x^y for an integer exponent y.
+func ratPow(x, y *big.Rat) (*big.Rat, error) {
+	if !y.IsInt() || !y.Num().IsInt64() {
+		return nil, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("%s ^ %s is not supported with big.Rat, the exponent must be an integer", ratText(x), ratText(y))}
+	}
+
+	n := y.Num().Int64()
+	if x.Sign() == 0 && n < 0 {
+		return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s ^ %d", ratText(x), n)}
+	}
+
+	if powTooLarge(x.Num(), n) || powTooLarge(x.Denom(), n) {
+		return nil, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("%s ^ %d is too large", ratText(x), n)}
+	}
+
+	exp := big.NewInt(n)
+	exp.Abs(exp)
+	num := new(big.Int).Exp(x.Num(), exp, nil)
+	den := new(big.Int).Exp(x.Denom(), exp, nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// ratFloor rounds x toward negative infinity.
+func ratFloor(x *big.Rat) *big.Rat {
+	// The denominator is always positive, so the Euclidean division rounds down.
+	return new(big.Rat).SetInt(new(big.Int).Div(x.Num(), x.Denom()))
+}
+
+// ratCeil rounds x toward positive infinity.
+func ratCeil(x *big.Rat) *big.Rat {
+	res := ratFloor(new(big.Rat).Neg(x))
+	return res.Neg(res)
+}
+
+// ratRound rounds x to the nearest integer, rounding halves away from zero.
+func ratRound(x *big.Rat) *big.Rat {
+	res := new(big.Rat).Abs(x)
+	res = ratFloor(res.Add(res, big.NewRat(1, 2)))
+	if x.Sign() < 0 {
+		res.Neg(res)
+	}
+	return res
+}
+
+func ratText(x *big.Rat) string {
+	return x.RatString()
+}
+
+func ratSum(values []*big.Rat) *big.Rat {
+	res := new(big.Rat)
+	for _, v := range values {
+		res.Add(res, v)
+	}
+	return res
+}
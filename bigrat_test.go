@@ -0,0 +1,81 @@
+package calc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestSolveRat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantKind calc.ErrorKind
+	}{
+		{name: "exact decimal sum", input: "0.1+0.2", want: "3/10"},
+		{name: "fractions", input: "1/3+1/6", want: "1/2"},
+		{name: "literal beyond float64", input: "1e400 / 4e399", want: "5/2"},
+		{name: "integer result", input: "((2*(5+3))+4)*(300/100)", want: "60"},
+		{name: "negative exponent", input: "2^-2", want: "1/4"},
+		{name: "fraction with exponent", input: "(2/3)^3", want: "8/27"},
+		{name: "floor", input: "FLOOR(-7/2)", want: "-4"},
+		{name: "ceil", input: "CEIL(-7/2)", want: "-3"},
+		{name: "round", input: "ROUND(5/2)", want: "3"},
+		{name: "round negative", input: "ROUND(-5/2)", want: "-3"},
+		{name: "round with digits", input: "ROUND(1/3, 2)", want: "33/100"},
+		{name: "average", input: "AVG(1, 2)", want: "3/2"},
+		{name: "clamp", input: "CLAMP(7/2, 0, 3)", want: "3"},
+		{name: "fractional exponent", input: "2^0.5", wantKind: calc.Unsupported},
+		{name: "irrational constant", input: "2*PI", wantKind: calc.Unsupported},
+		{name: "irrational function", input: "SQRT(4)", wantKind: calc.Unsupported},
+		{name: "unknown function", input: "LOOL(4)", wantKind: calc.UnknownFunction},
+		{name: "unknown identifier", input: "LOOL", wantKind: calc.UnknownIdentifier},
+		{name: "division by zero", input: "1/(1-1)", wantKind: calc.DivisionByZero},
+		{name: "zero with negative exponent", input: "0^-1", wantKind: calc.DivisionByZero},
+		{name: "one with huge exponent", input: "1^(10^10)", want: "1"},
+		{name: "huge power", input: "3^(10^10)", wantKind: calc.Overflow},
+		{name: "huge power of fraction", input: "POW(1/3, -(10^10))", wantKind: calc.Overflow},
+		{name: "comparison", input: "1 < 2", wantKind: calc.Unsupported},
+		{name: "floor division", input: "(7/2) // (1/3)", want: "10"},
+		{name: "modulo", input: "(7/2) % (1/3)", want: "1/6"},
+		{name: "modulo by zero", input: "1 % 0", wantKind: calc.DivisionByZero},
+		{name: "factorial", input: "25!", want: "15511210043330985984000000"},
+		{name: "factorial of fraction", input: "(1/2)!", wantKind: calc.Unsupported},
+		{name: "conditional", input: "1 ? 2 : 3", wantKind: calc.Unsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.SolveRat(tt.input)
+			if tt.wantKind != 0 {
+				var evalErr *calc.EvalError
+				if !errors.As(err, &evalErr) || evalErr.Kind != tt.wantKind {
+					t.Errorf("SolveRat() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("SolveRat() error = %v", err)
+			}
+
+			if got.RatString() != tt.want {
+				t.Errorf("SolveRat() got = %v, want %v", got.RatString(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalRat(t *testing.T) {
+	tree, err := calc.ParseExpr("x/4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calc.EvalRat(tree, calc.Env{"X": 0.5})
+	if err != nil {
+		t.Fatalf("EvalRat() error = %v", err)
+	}
+	if got.RatString() != "1/8" {
+		t.Errorf("EvalRat() got = %v, want %v", got.RatString(), "1/8")
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/aligator/calc/cmd/calc
+
+go 1.16
+
+require (
+	github.com/aligator/calc v0.0.0-00010101000000-000000000000
+	github.com/peterh/liner v1.2.2
+)
+
+// The command is built from the same checkout as the library.
+replace github.com/aligator/calc => ../..
@@ -0,0 +1,223 @@
+// Command calc solves mathematical expressions.
+//
+// Without arguments it starts an interactive REPL if the standard input is a
+// terminal, otherwise it solves the expressions from the standard input line
+// by line. Arguments are joined and solved as a single expression:
+//
+//	calc '2*(3+4)'
+//	echo 'x = 3*4
+//	x^2' | calc
+//
+// See :help in the REPL for variables and commands.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aligator/calc"
+	"github.com/peterh/liner"
+)
+
+func main() {
+	historyFile := flag.String("history", defaultHistoryFile(), "file which stores the history of the REPL, empty to disable it")
+	notation := flag.String("format", "shortest", "notation of the results: shortest, fixed, significant, engineering, si, fraction, hex or binary")
+	precision := flag.Int("precision", -1, "digits after the decimal point for fixed, significant digits for significant, engineering and si, negative for as many as needed")
+	decimal := flag.String("decimal", ".", "decimal separator of the results")
+	group := flag.String("group", "", "separator of groups of three digits in the results, such as ','")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [expression]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	s := newSession(os.Stdout, os.Stderr)
+	format, err := formatOptions(*notation, *precision, *decimal, *group)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	s.format = format
+
+	var ok bool
+	switch {
+	case flag.NArg() > 0:
+		ok = runExpr(s, strings.Join(flag.Args(), " "))
+	case isTerminal(os.Stdin):
+		ok = runREPL(s, *historyFile)
+	default:
+		ok = runScript(s, os.Stdin)
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// formatOptions converts the values of the format flags into calc.FormatOptions.
+func formatOptions(notation string, precision int, decimal, group string) (calc.FormatOptions, error) {
+	n, err := calc.ParseNotation(notation)
+	if err != nil {
+		return calc.FormatOptions{}, err
+	}
+	opts := calc.FormatOptions{Notation: n, Precision: precision}
+
+	if opts.Decimal, err = separator("decimal", decimal); err != nil {
+		return calc.FormatOptions{}, err
+	}
+	if opts.Decimal == 0 {
+		return calc.FormatOptions{}, errors.New("the decimal separator must not be empty")
+	}
+	if opts.Group, err = separator("group", group); err != nil {
+		return calc.FormatOptions{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return calc.FormatOptions{}, err
+	}
+	return opts, nil
+}
+
+// separator returns the single rune of a separator flag or 0 if it is empty.
+func separator(name, value string) (rune, error) {
+	runes := []rune(value)
+	switch len(runes) {
+	case 0:
+		return 0, nil
+	case 1:
+		return runes[0], nil
+	}
+	return 0, fmt.Errorf("the %s separator must be a single character, got %q", name, value)
+}
+
+// runExpr solves a single expression and reports whether this succeeded.
+func runExpr(s *session, expr string) bool {
+	if err := s.handle(expr); err != nil && !errors.Is(err, errQuit) {
+		s.printError(expr, err)
+		return false
+	}
+	return true
+}
+
+// runScript solves the expressions read from r line by line and reports
+// whether all of them succeeded.
+func runScript(s *session, r io.Reader) bool {
+	ok := true
+	var input string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		input = joinLine(input, scanner.Text())
+		if needsMore(input) {
+			continue
+		}
+
+		err := s.handle(input)
+		if errors.Is(err, errQuit) {
+			return ok
+		} else if err != nil {
+			s.printError(input, err)
+			ok = false
+		}
+		input = ""
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(s.errOut, "error:", err)
+		return false
+	}
+
+	// Solve an unfinished input to report what is missing.
+	if strings.TrimSpace(input) != "" {
+		return runExpr(s, input) && ok
+	}
+	return ok
+}
+
+// runREPL reads the expressions interactively until the user quits.
+func runREPL(s *session, historyFile string) bool {
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(s.complete)
+
+	if historyFile != "" {
+		if f, err := os.Open(historyFile); err == nil {
+			_, _ = line.ReadHistory(f)
+			_ = f.Close()
+		}
+	}
+
+	fmt.Fprintln(s.out, "Enter :help for help and :quit to exit.")
+	var input string
+	for {
+		prompt := "> "
+		if input != "" {
+			prompt = "... "
+		}
+
+		text, err := line.Prompt(prompt)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			// Ctrl-C discards the current input.
+			input = ""
+			continue
+		} else if errors.Is(err, io.EOF) {
+			fmt.Fprintln(s.out)
+			break
+		} else if err != nil {
+			fmt.Fprintln(s.errOut, "error:", err)
+			return false
+		}
+
+		input = joinLine(input, text)
+		if needsMore(input) {
+			continue
+		}
+		if strings.TrimSpace(input) != "" {
+			line.AppendHistory(strings.ReplaceAll(input, "\n", " "))
+		}
+
+		err = s.handle(input)
+		if errors.Is(err, errQuit) {
+			break
+		} else if err != nil {
+			s.printError(input, err)
+		}
+		input = ""
+	}
+
+	if historyFile != "" {
+		if err := writeHistory(line, historyFile); err != nil {
+			fmt.Fprintln(s.errOut, "error: writing the history:", err)
+		}
+	}
+	return true
+}
+
+func writeHistory(line *liner.State, historyFile string) error {
+	f, err := os.Create(historyFile)
+	if err != nil {
+		return err
+	}
+	if _, err := line.WriteHistory(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".calc_history")
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
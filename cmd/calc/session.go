@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aligator/calc"
+)
+
+// ansName is the variable which holds the previous result.
+const ansName = "ANS"
+
+// errQuit is returned by handle if the user wants to quit.
+var errQuit = errors.New("quit")
+
+// session evaluates the inputs of the REPL or a script and keeps the
+// variables and functions between them.
+type session struct {
+	script *calc.Script
+	// format configures how results are printed.
+	format calc.FormatOptions
+	out    io.Writer
+	errOut io.Writer
+}
+
+func newSession(out, errOut io.Writer) *session {
+	return &session{script: calc.NewScript(nil, calc.ScriptOptions{}), out: out, errOut: errOut}
+}
+
+// handle evaluates an input, which is either a command such as :vars or
+// statements such as x = 3*4, f(x) = x^2 or expressions, and prints the
+// result of the last statement.
+func (s *session) handle(input string) error {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return nil
+	}
+	if strings.HasPrefix(trimmed, ":") {
+		return s.command(trimmed)
+	}
+
+	res, err := s.script.Eval(strings.NewReader(input))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case res.Func:
+		def, _ := s.script.Definition(res.Name)
+		fmt.Fprintln(s.out, def)
+		return nil
+	case res.Name != "":
+		fmt.Fprintf(s.out, "%s = %s\n", res.Name, s.formatValue(res.Value))
+	default:
+		fmt.Fprintln(s.out, s.formatValue(res.Value))
+	}
+	s.script.Vars[ansName] = res.Value
+	return nil
+}
+
+// printError prints an error of handle together with the position in the input.
+func (s *session) printError(input string, err error) {
+	// The position already tells which statement failed.
+	var scriptErr *calc.ScriptError
+	if errors.As(err, &scriptErr) {
+		err = scriptErr.Err
+	}
+
+	if caret := calc.Caret(input, calc.ErrorPosition(err)); caret != "" {
+		fmt.Fprintln(s.errOut, caret)
+	}
+	fmt.Fprintln(s.errOut, "error:", err)
+}
+
+func (s *session) command(cmd string) error {
+	switch cmd {
+	case ":vars":
+		s.printValues(s.script.Vars)
+	case ":consts":
+		s.printValues(calc.Constants())
+	case ":funcs":
+		for _, name := range calc.DefaultRegistry.Names() {
+			fmt.Fprintln(s.out, name)
+		}
+		for _, name := range s.script.Funcs() {
+			def, _ := s.script.Definition(name)
+			fmt.Fprintln(s.out, def)
+		}
+	case ":help":
+		fmt.Fprint(s.out, helpText)
+	case ":quit", ":q":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %s, see :help", cmd)
+	}
+	return nil
+}
+
+func (s *session) printValues(values calc.Env) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%s = %s\n", name, s.formatValue(values[name]))
+	}
+}
+
+// complete returns the completions of the identifier at the end of line.
+func (s *session) complete(line string) []string {
+	start := len(line)
+	for start > 0 {
+		ch, size := utf8.DecodeLastRuneInString(line[:start])
+		if !calc.DefaultIdentRune(ch, 1) {
+			break
+		}
+		start -= size
+	}
+	prefix := strings.ToUpper(line[start:])
+	if prefix == "" {
+		return nil
+	}
+
+	var res []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, line[:start]+name)
+		}
+	}
+	for _, name := range calc.DefaultRegistry.Names() {
+		add(name + "(")
+	}
+	for name := range calc.Constants() {
+		add(name)
+	}
+	for _, name := range s.script.Funcs() {
+		add(name + "(")
+	}
+	for name := range s.script.Vars {
+		add(name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+const helpText = `Enter an expression such as 2*(3+4) to solve it.
+  x = 3*4   assigns the result to the variable x
+  f(x) = x^2
+            defines the function f
+  ans       is the previous result
+  ;         separates several statements in one line
+  \         at the end of a line continues the input in the next line,
+            which also happens if there are unclosed parentheses
+Commands:
+  :vars     lists the variables
+  :consts   lists the built-in constants
+  :funcs    lists the functions
+  :help     shows this help
+  :quit     exits
+`
+
+// needsMore reports whether the input continues in the next line, which is
+// the case if it ends with a backslash or has unclosed parentheses.
+func needsMore(input string) bool {
+	if strings.HasSuffix(strings.TrimRightFunc(input, isSpace), `\`) {
+		return true
+	}
+	return strings.Count(input, "(") > strings.Count(input, ")")
+}
+
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+// joinLine appends a line to the previous lines of a multi-line input.
+// A backslash joins the lines with a space, because a line break would
+// start a new statement.
+func joinLine(input, line string) string {
+	trimmed := strings.TrimRightFunc(input, isSpace)
+	if trimmed == "" {
+		return line
+	}
+	if strings.HasSuffix(trimmed, `\`) {
+		return strings.TrimSuffix(trimmed, `\`) + " " + line
+	}
+	return trimmed + "\n" + line
+}
+
+// formatValue formats v using the format of the session.
+// Values which the format cannot represent, such as 1.5 in hexadecimal,
+// use the shortest decimal notation instead.
+func (s *session) formatValue(v float64) string {
+	text, err := calc.Format(v, s.format)
+	if err != nil {
+		text, _ = calc.Format(v, calc.FormatOptions{Decimal: s.format.Decimal, Group: s.format.Group})
+	}
+	return text
+}
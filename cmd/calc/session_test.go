@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestRunScript(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		format     calc.FormatOptions
+		wantOut    string
+		wantErrOut string
+		wantOK     bool
+	}{
+		{name: "expressions", input: "1+2\n2*(3+4)\n", wantOut: "3\n14\n", wantOK: true},
+		{name: "empty lines", input: "\n1\n  \n", wantOut: "1\n", wantOK: true},
+		{name: "assignment", input: "x = 3*4\nx^2\n", wantOut: "X = 12\n144\n", wantOK: true},
+		{name: "comparison is no assignment", input: "x = 2\nx == 2\n", wantOut: "X = 2\n1\n", wantOK: true},
+		{name: "previous result", input: "2*3\nans+1\n", wantOut: "6\n7\n", wantOK: true},
+		{name: "multi-line input", input: "(1 +\n2) * \\\n3\n", wantOut: "9\n", wantOK: true},
+		{name: "list variables", input: "b = 2\na = 1\n:vars\n", wantOut: "B = 2\nA = 1\nA = 1\nANS = 1\nB = 2\n", wantOK: true},
+		{name: "function", input: "f(x, y) = x^2 + y^2\nf(3, 4)\n", wantOut: "F(X, Y) = ((X ^ 2) + (Y ^ 2))\n25\n", wantOK: true},
+		{name: "recursive function", input: "fact(n) = n <= 1 ? 1 : n * fact(n - 1)\nfact(5)\n", wantOut: "FACT(N) = ((N <= 1) ? 1 : (N * FACT((N - 1))))\n120\n", wantOK: true},
+		{name: "several statements", input: "a = 1; b = a + 1\n", wantOut: "B = 2\n", wantOK: true},
+		{name: "list functions", input: "sq(x) = x*x\n:funcs\n", wantOut: "SQ(X) = (X * X)\n" + strings.Join(calc.DefaultRegistry.Names(), "\n") + "\nSQ(X) = (X * X)\n", wantOK: true},
+		{
+			name:       "error in function",
+			input:      "g(x) = 1 / x\n2 + g(0)\n",
+			wantOut:    "G(X) = (1 / X)\n",
+			wantErrOut: "2 + g(0)\n    ^\nerror: 1:5: division by zero: 1 / 0\n",
+		},
+		{name: "quit", input: "1\n:quit\n2\n", wantOut: "1\n", wantOK: true},
+		{
+			name:       "error",
+			input:      "1 + 1/0\n2\n",
+			wantOut:    "2\n",
+			wantErrOut: "1 + 1/0\n     ^\nerror: 1:6: division by zero: 1 / 0\n",
+		},
+		{
+			name:       "error in assignment",
+			input:      "x = 1 + y\n",
+			wantErrOut: "x = 1 + y\n        ^\nerror: 1:9: undefined variable Y\n",
+		},
+		{name: "unknown command", input: ":foo\n", wantErrOut: "error: unknown command :foo, see :help\n"},
+		{name: "format", input: "1234.5\nx = 1/4\n", format: calc.FormatOptions{Notation: calc.Fixed, Precision: 2, Group: ','}, wantOut: "1,234.50\nX = 0.25\n", wantOK: true},
+		{name: "format fallback", input: "255\n1.5\n", format: calc.FormatOptions{Notation: calc.Hex}, wantOut: "0xff\n1.5\n", wantOK: true},
+		{name: "unfinished input", input: "(1 +\n", wantErrOut: "(1 +\n^\nerror: 1:1: 1 parenthesis not closed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			s := newSession(&out, &errOut)
+			s.format = tt.format
+
+			ok := runScript(s, strings.NewReader(tt.input))
+			if ok != tt.wantOK {
+				t.Errorf("runScript() = %v, want %v", ok, tt.wantOK)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("runScript() out = %q, want %q", out.String(), tt.wantOut)
+			}
+			if errOut.String() != tt.wantErrOut {
+				t.Errorf("runScript() errOut = %q, want %q", errOut.String(), tt.wantErrOut)
+			}
+		})
+	}
+}
+
+func TestSession_Complete(t *testing.T) {
+	s := newSession(&bytes.Buffer{}, &bytes.Buffer{})
+	s.script.Vars["SQUARE"] = 4
+	if err := s.handle("sqr(x) = x*x"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.complete("1 + sq")
+	want := []string{"1 + SQR(", "1 + SQRT(", "1 + SQRT2", "1 + SQRTE", "1 + SQRTPHI", "1 + SQRTPI", "1 + SQUARE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("complete() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		notation  string
+		precision int
+		decimal   string
+		group     string
+		want      calc.FormatOptions
+		wantErr   string
+	}{
+		{name: "defaults", notation: "shortest", precision: -1, decimal: ".", want: calc.FormatOptions{Precision: -1, Decimal: '.'}},
+		{name: "german", notation: "Fixed", precision: 2, decimal: ",", group: ".", want: calc.FormatOptions{Notation: calc.Fixed, Precision: 2, Decimal: ',', Group: '.'}},
+		{name: "unicode group", notation: "si", decimal: ".", group: "\u202f", want: calc.FormatOptions{Notation: calc.SI, Decimal: '.', Group: '\u202f'}},
+		{name: "unknown notation", notation: "roman", decimal: ".", wantErr: "unknown notation roman"},
+		{name: "empty decimal", notation: "fixed", wantErr: "the decimal separator must not be empty"},
+		{name: "same separators", notation: "fixed", decimal: ",", group: ",", wantErr: "the decimal and the group separator are both ','"},
+		{name: "digit as decimal", notation: "fixed", decimal: "5", wantErr: "invalid decimal separator '5'"},
+		{name: "long group", notation: "fixed", decimal: ".", group: ", ", wantErr: "the group separator must be a single character, got \", \""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatOptions(tt.notation, tt.precision, tt.decimal, tt.group)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("formatOptions() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("formatOptions() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
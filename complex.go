@@ -0,0 +1,203 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+type complexFunc struct {
+	arity
+	fn func(args []complex128) complex128
+}
+
+// complexParts applies f to the real and imaginary part separately.
+func complexParts(f func(float64) float64) func(args []complex128) complex128 {
+	return func(args []complex128) complex128 {
+		return complex(f(real(args[0])), f(imag(args[0])))
+	}
+}
+
+func complexUnary(f func(complex128) complex128) func(args []complex128) complex128 {
+	return func(args []complex128) complex128 {
+		return f(args[0])
+	}
+}
+
+var complexFuncs = map[string]complexFunc{
+	"LN":   {arity{1, 1}, complexUnary(cmplx.Log)},
+	"COS":  {arity{1, 1}, complexUnary(cmplx.Cos)},
+	"SIN":  {arity{1, 1}, complexUnary(cmplx.Sin)},
+	"TAN":  {arity{1, 1}, complexUnary(cmplx.Tan)},
+	"ACOS": {arity{1, 1}, complexUnary(cmplx.Acos)},
+	"ASIN": {arity{1, 1}, complexUnary(cmplx.Asin)},
+	"ATAN": {arity{1, 1}, complexUnary(cmplx.Atan)},
+	"SQRT": {arity{1, 1}, complexUnary(cmplx.Sqrt)},
+	"CBRT": {arity{1, 1}, complexUnary(func(x complex128) complex128 {
+		// Real numbers keep their real cube root instead of the principal one.
+		if imag(x) == 0 {
+			return complex(math.Cbrt(real(x)), 0)
+		}
+		return cmplx.Pow(x, 1.0/3)
+	})},
+	"ABS": {arity{1, 1}, complexUnary(func(x complex128) complex128 {
+		return complex(cmplx.Abs(x), 0)
+	})},
+	"CEIL":  {arity{1, 1}, complexParts(math.Ceil)},
+	"FLOOR": {arity{1, 1}, complexParts(math.Floor)},
+	"RE": {arity{1, 1}, complexUnary(func(x complex128) complex128 {
+		return complex(real(x), 0)
+	})},
+	"IM": {arity{1, 1}, complexUnary(func(x complex128) complex128 {
+		return complex(imag(x), 0)
+	})},
+	"ARG": {arity{1, 1}, complexUnary(func(x complex128) complex128 {
+		return complex(cmplx.Phase(x), 0)
+	})},
+	"CONJ": {arity{1, 1}, complexUnary(cmplx.Conj)},
+	"POW": {arity{2, 2}, func(args []complex128) complex128 {
+		return cmplx.Pow(args[0], args[1])
+	}},
+}
+
+// SolveComplex solves a mathematical calculation using complex128 values.
+func SolveComplex(s string) (complex128, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return EvalComplex(tree, nil)
+}
+
+// EvalComplex evaluates the abstract syntax tree of an expression using
+// complex128 values, so that for example SQRT(-1) is i.
+//
+// Imaginary numbers are written as literals with the suffix i, such as 2i,
+// or using the constant I. All single-argument built-in functions and POW
+// are available as complex functions, CEIL and FLOOR round the real and
+// imaginary parts separately. RE, IM, ARG and CONJ return the real part,
+// the imaginary part, the phase and the complex conjugate.
+func EvalComplex(n Node, vars Env) (complex128, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.overflows() {
+			return 0, literalOverflow(n, "complex128")
+		}
+		if n.Imag {
+			return complex(0, n.Value), nil
+		}
+		return complex(n.Value, 0), nil
+	case *ConstRef:
+		if _, ok := vars[n.Name]; !ok && strings.ToUpper(n.Name) == "I" {
+			return 1i, nil
+		}
+		val, err := vars.Lookup(n.Name)
+		return complex(val, 0), at(err, n.Pos)
+	case *UnaryOp:
+		x, err := EvalComplex(n.X, vars)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			// -x would turn a zero imaginary part into -0, which is
+			// on the other side of the branch cut of SQRT and LN.
+			return 0 - x, nil
+		}
+		return 0, at(unsupportedOperator(n.Op, "complex128"), n.Pos)
+	case *BinaryOp:
+		x, err := EvalComplex(n.X, vars)
+		if err != nil {
+			return 0, err
+		}
+		y, err := EvalComplex(n.Y, vars)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := complexBinary(n.Op, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		return 0, at(unsupportedOperator("?:", "complex128"), n.Pos)
+	case *Call:
+		f, ok := complexFuncs[n.Name]
+		if !ok {
+			return 0, at(unsupportedFunction(n.Name, "complex128"), n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return 0, at(err, n.Pos)
+		}
+
+		args := make([]complex128, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := EvalComplex(arg, vars)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = val
+		}
+
+		res := f.fn(args)
+		if cmplx.IsNaN(res) && !complexContainsNaN(args) {
+			return 0, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: fmt.Sprintf("%s is not defined for %v", n.Name, args)}
+		}
+		return res, nil
+	}
+
+	return 0, fmt.Errorf("unsupported node %T", n)
+}
+
+func complexBinary(op string, x, y complex128) (complex128, error) {
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s / %s", FormatComplex(x), FormatComplex(y))}
+		}
+		return x / y, nil
+	case "^":
+		return cmplx.Pow(x, y), nil
+	}
+	return 0, unsupportedOperator(op, "complex128")
+}
+
+// FormatComplex formats a complex number like 1.5, 2i, 1+2i or 1-2i.
+// Zero parts are omitted.
+func FormatComplex(c complex128) string {
+	re := strconv.FormatFloat(real(c), 'g', -1, 64)
+	im := strconv.FormatFloat(imag(c), 'g', -1, 64)
+
+	switch {
+	case imag(c) == 0:
+		return re
+	case real(c) == 0:
+		return im + "i"
+	case strings.HasPrefix(im, "-") || strings.HasPrefix(im, "+"):
+		return re + im + "i"
+	}
+	return re + "+" + im + "i"
+}
+
+func complexContainsNaN(values []complex128) bool {
+	for _, v := range values {
+		if cmplx.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func imaginaryUnsupported(n *NumberLit, mode string) error {
+	return &EvalError{Kind: Unsupported, Pos: n.Pos, Msg: fmt.Sprintf("imaginary number %s is not supported with %s", n, mode)}
+}
@@ -0,0 +1,93 @@
+package calc_test
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestSolveComplex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     complex128
+		wantKind calc.ErrorKind
+	}{
+		{name: "real", input: "1+2", want: 3},
+		{name: "imaginary literal", input: "2i", want: 2i},
+		{name: "imaginary constant", input: "I*I", want: -1},
+		{name: "complex sum", input: "1+2i+3-1i", want: 4 + 1i},
+		{name: "negative imaginary literal", input: "-2i", want: -2i},
+		{name: "multiplication", input: "(1+2i)*(3-1i)", want: 5 + 5i},
+		{name: "division", input: "(5+5i)/(3-1i)", want: 1 + 2i},
+		{name: "square root of minus one", input: "SQRT(-1)", want: 1i},
+		{name: "power", input: "I^2", want: -1},
+		{name: "real part", input: "RE(3+4i)", want: 3},
+		{name: "imaginary part", input: "IM(3+4i)", want: 4},
+		{name: "absolute value", input: "ABS(3+4i)", want: 5},
+		{name: "argument", input: "ARG(2i)", want: math.Pi / 2},
+		{name: "conjugate", input: "CONJ(3+4i)", want: 3 - 4i},
+		{name: "real cube root", input: "CBRT(-8)", want: -2},
+		{name: "floor", input: "FLOOR(1.5-1.5i)", want: 1 - 2i},
+		{name: "logarithm of negative", input: "LN(-1)", want: math.Pi * 1i},
+		{name: "constant", input: "PI*I", want: math.Pi * 1i},
+		{name: "division by zero", input: "1/(I-I)", wantKind: calc.DivisionByZero},
+		{name: "unsupported function", input: "MAX(1, I)", wantKind: calc.Unsupported},
+		{name: "wrong argument count", input: "RE(1, 2)", wantKind: calc.ArgumentCount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.SolveComplex(tt.input)
+			if tt.wantKind != 0 {
+				var evalErr *calc.EvalError
+				if !errors.As(err, &evalErr) || evalErr.Kind != tt.wantKind {
+					t.Errorf("SolveComplex() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("SolveComplex() error = %v", err)
+			}
+
+			if cmplx.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("SolveComplex() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImaginaryUnsupported(t *testing.T) {
+	_, err := calc.Solve("1+2i")
+	var evalErr *calc.EvalError
+	if !errors.As(err, &evalErr) || evalErr.Kind != calc.Unsupported {
+		t.Fatalf("Solve() error = %v, want %v", err, calc.Unsupported)
+	}
+	if want := (calc.Position{Offset: 2, Line: 1, Column: 3}); evalErr.Pos != want {
+		t.Errorf("Solve() error position = %+v, want %+v", evalErr.Pos, want)
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	tests := []struct {
+		in   complex128
+		want string
+	}{
+		{in: 0, want: "0"},
+		{in: 1.5, want: "1.5"},
+		{in: 2i, want: "2i"},
+		{in: -2i, want: "-2i"},
+		{in: 1 + 2i, want: "1+2i"},
+		{in: 1 - 2i, want: "1-2i"},
+		{in: complex(1, math.Inf(1)), want: "1+Infi"},
+		{in: complex(1, math.NaN()), want: "1+NaNi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := calc.FormatComplex(tt.in); got != tt.want {
+				t.Errorf("FormatComplex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode defines how decimal results are rounded if they have more
+// fraction digits than allowed.
+type RoundingMode int
+
+// These constants are all possible RoundingMode values.
+const (
+	// RoundExact does not round at all but returns an error if a result
+	// cannot be represented exactly.
+	RoundExact RoundingMode = iota
+	// RoundHalfEven rounds to the nearest neighbor and halves to the even neighbor.
+	RoundHalfEven
+	// RoundHalfUp rounds to the nearest neighbor and halves away from zero.
+	RoundHalfUp
+	// RoundHalfDown rounds to the nearest neighbor and halves toward zero.
+	RoundHalfDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds toward zero.
+	RoundDown
+	// RoundCeiling rounds toward positive infinity.
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor
+)
+
+// DecimalOptions configure the decimal evaluation mode.
+type DecimalOptions struct {
+	// Scale is the amount of fraction digits of the result and of the
+	// results of divisions. It must not be negative.
+	Scale int
+	// Rounding is used whenever a result has more fraction digits than Scale.
+	// The default RoundExact returns an error instead.
+	Rounding RoundingMode
+}
+
+// Decimal is a decimal number with a fixed amount of fraction digits.
+// Its value is unscaled * 10^-scale.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// Scale returns the amount of fraction digits.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Unscaled returns the decimal as integer without its decimal point.
+func (d Decimal) Unscaled() *big.Int {
+	return new(big.Int).Set(d.int())
+}
+
+// Rat returns the exact value of the decimal.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10(d.scale))
+}
+
+// Float64 returns the nearest float64 value of the decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns the decimal with exactly Scale fraction digits.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+
+	if d.int().Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// maxErrorDigits is the length up to which errors contain a decimal.
+const maxErrorDigits = 40
+
+// short returns the decimal like String, but long decimals are shortened to
+// their first and last digits, so that errors stay readable.
+func (d Decimal) short() string {
+	s := d.String()
+	if len(s) <= maxErrorDigits {
+		return s
+	}
+	return fmt.Sprintf("%s...%s (%d digits)", s[:maxErrorDigits/2], s[len(s)-maxErrorDigits/2:], len(s))
+}
+
+// int returns the unscaled value, which is nil for the zero value of Decimal.
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the decimal with the given scale, rounded if necessary.
+func (d Decimal) rescale(scale int, mode RoundingMode) (Decimal, error) {
+	if scale >= d.scale {
+		return Decimal{unscaled: new(big.Int).Mul(d.int(), pow10(scale-d.scale)), scale: scale}, nil
+	}
+
+	res, err := roundQuo(d.int(), pow10(d.scale-scale), mode)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%s cannot be represented with %d fraction digits: %w", d.short(), scale, err)
+	}
+	return Decimal{unscaled: res, scale: scale}, nil
+}
+
+// SolveDecimal solves a mathematical calculation using exact decimal arithmetic.
+func SolveDecimal(s string, opts DecimalOptions) (Decimal, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	return EvalDecimal(tree, nil, opts)
+}
+
+// EvalDecimal evaluates the abstract syntax tree of an expression using exact
+// decimal arithmetic. This avoids the binary rounding errors of float64, for
+// example 0.1+0.2 is exactly 0.3.
+//
+// Addition, subtraction and multiplication are always exact. Divisions and
+// the final result are rounded to opts.Scale fraction digits using
+// opts.Rounding, which by default returns an error instead of rounding.
+// "^" needs an integer exponent, the irrational built-in constants are not
+// available and only the built-in functions ABS, CEIL, FLOOR, MIN, MAX, SUM,
+// AVG, POW, FACT, ROUND and CLAMP can be called.
+//
+// Variables are converted using their shortest decimal representation,
+// so a variable set to 0.1 is exactly 0.1.
+func EvalDecimal(n Node, vars Env, opts DecimalOptions) (Decimal, error) {
+	if opts.Scale < 0 {
+		return Decimal{}, fmt.Errorf("decimal scale must not be negative, got %d", opts.Scale)
+	}
+
+	e := decimalEvaluator{vars: vars, opts: opts}
+	res, err := e.eval(n)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	res, err = res.rescale(opts.Scale, opts.Rounding)
+	if err != nil {
+		return Decimal{}, &EvalError{Kind: InexactResult, Msg: "result", Err: err}
+	}
+	return res, nil
+}
+
+type decimalFunc struct {
+	arity
+	fn func(e decimalEvaluator, args []Decimal) (Decimal, error)
+}
+
+var decimalFuncs = map[string]decimalFunc{
+	"ABS": {arity{1, 1}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		return Decimal{unscaled: new(big.Int).Abs(args[0].int()), scale: args[0].scale}, nil
+	}},
+	"CEIL": {arity{1, 1}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		return args[0].rescale(0, RoundCeiling)
+	}},
+	"FLOOR": {arity{1, 1}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		return args[0].rescale(0, RoundFloor)
+	}},
+	"MIN": {arity{1, Variadic}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if decimalCmp(v, res) < 0 {
+				res = v
+			}
+		}
+		return res, nil
+	}},
+	"MAX": {arity{1, Variadic}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if decimalCmp(v, res) > 0 {
+				res = v
+			}
+		}
+		return res, nil
+	}},
+	"SUM": {arity{1, Variadic}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		return decimalSum(args), nil
+	}},
+	"AVG": {arity{1, Variadic}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		count := Decimal{unscaled: big.NewInt(int64(len(args)))}
+		return e.quo(decimalSum(args), count)
+	}},
+	"POW": {arity{2, 2}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		return e.pow(args[0], args[1])
+	}},
+	"FACT": {arity{1, 1}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		n, err := args[0].rescale(0, RoundExact)
+		if err != nil {
+			return Decimal{}, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("FACT of %s is not supported with decimals, it needs an integer", args[0])}
+		}
+		res, err := intFactorial(n.int())
+		if err != nil {
+			return Decimal{}, err
+		}
+		return Decimal{unscaled: res}, nil
+	}},
+	"ROUND": {arity{1, 2}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		digits := 0
+		if len(args) == 2 {
+			d, err := args[1].rescale(0, RoundExact)
+			if err != nil || !d.int().IsInt64() {
+				return Decimal{}, &EvalError{Kind: FunctionError, Msg: "ROUND", Err: fmt.Errorf("digits must be an integer, got %s", args[1])}
+			}
+			digits = int(d.int().Int64())
+		}
+
+		// Rounding is explicitly requested here, so RoundExact falls back to RoundHalfUp.
+		mode := e.opts.Rounding
+		if mode == RoundExact {
+			mode = RoundHalfUp
+		}
+		if digits < 0 {
+			// Round x / 10^-digits to an integer and scale it back.
+			res, err := Decimal{unscaled: args[0].int(), scale: args[0].scale - digits}.rescale(0, mode)
+			if err != nil {
+				return Decimal{}, err
+			}
+			return Decimal{unscaled: new(big.Int).Mul(res.int(), pow10(-digits))}, nil
+		}
+		return args[0].rescale(digits, mode)
+	}},
+	"CLAMP": {arity{3, 3}, func(e decimalEvaluator, args []Decimal) (Decimal, error) {
+		if decimalCmp(args[1], args[2]) > 0 {
+			return Decimal{}, &EvalError{Kind: FunctionError, Msg: "CLAMP", Err: fmt.Errorf("lower bound %s is greater than upper bound %s", args[1], args[2])}
+		}
+		res := args[0]
+		if decimalCmp(res, args[1]) < 0 {
+			res = args[1]
+		} else if decimalCmp(res, args[2]) > 0 {
+			res = args[2]
+		}
+		return res, nil
+	}},
+}
+
+type decimalEvaluator struct {
+	vars Env
+	opts DecimalOptions
+}
+
+func (e decimalEvaluator) eval(n Node) (Decimal, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return Decimal{}, imaginaryUnsupported(n, "decimals")
+		}
+		text := n.Text
+		if text == "" {
+			text = strconv.FormatFloat(n.Value, 'g', -1, 64)
+		}
+		res, ok := parseDecimal(text)
+		if !ok {
+			return Decimal{}, &EvalError{Kind: InvalidNumber, Pos: n.Pos, Msg: fmt.Sprintf("invalid number %s", text)}
+		}
+		return res, nil
+	case *ConstRef:
+		if val, ok := e.vars[n.Name]; ok {
+			if math.IsNaN(val) || math.IsInf(val, 0) {
+				return Decimal{}, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: fmt.Sprintf("variable %s is not a decimal number: %v", n.Name, val)}
+			}
+			res, _ := parseDecimal(strconv.FormatFloat(val, 'g', -1, 64))
+			return res, nil
+		}
+		if _, ok := consts[strings.ToUpper(n.Name)]; ok {
+			return Decimal{}, &EvalError{Kind: Unsupported, Pos: n.Pos, Msg: fmt.Sprintf("constant %s is not supported with decimals", n.Name)}
+		}
+		_, err := e.vars.Lookup(n.Name)
+		return Decimal{}, at(err, n.Pos)
+	case *UnaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return Decimal{}, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			return Decimal{unscaled: new(big.Int).Neg(x.int()), scale: x.scale}, nil
+		}
+		return Decimal{}, at(unsupportedOperator(n.Op, "decimals"), n.Pos)
+	case *BinaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return Decimal{}, err
+		}
+		y, err := e.eval(n.Y)
+		if err != nil {
+			return Decimal{}, err
+		}
+
+		res, err := e.binary(n.Op, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		return Decimal{}, at(unsupportedOperator("?:", "decimals"), n.Pos)
+	case *Call:
+		f, ok := decimalFuncs[n.Name]
+		if !ok {
+			return Decimal{}, at(unsupportedFunction(n.Name, "decimals"), n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return Decimal{}, at(err, n.Pos)
+		}
+
+		args := make([]Decimal, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.eval(arg)
+			if err != nil {
+				return Decimal{}, err
+			}
+			args[i] = val
+		}
+
+		res, err := f.fn(e, args)
+		return res, at(err, n.Pos)
+	}
+
+	return Decimal{}, fmt.Errorf("unsupported node %T", n)
+}
+
+func (e decimalEvaluator) binary(op string, x, y Decimal) (Decimal, error) {
+	switch op {
+	case "+", "-":
+		scale := x.scale
+		if y.scale > scale {
+			scale = y.scale
+		}
+		x, _ = x.rescale(scale, RoundExact)
+		y, _ = y.rescale(scale, RoundExact)
+
+		res := new(big.Int)
+		if op == "+" {
+			res.Add(x.int(), y.int())
+		} else {
+			res.Sub(x.int(), y.int())
+		}
+		return Decimal{unscaled: res, scale: scale}, nil
+	case "*":
+		return Decimal{unscaled: new(big.Int).Mul(x.int(), y.int()), scale: x.scale + y.scale}, nil
+	case "/":
+		return e.quo(x, y)
+	case "//", "%":
+		if y.int().Sign() == 0 {
+			return Decimal{}, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s %s %s", x.short(), op, y)}
+		}
+
+		quo := Decimal{unscaled: ratFloor(new(big.Rat).Quo(x.Rat(), y.Rat())).Num()}
+		if op == "//" {
+			return quo, nil
+		}
+		mul, _ := e.binary("*", quo, y)
+		return e.binary("-", x, mul)
+	case "^":
+		return e.pow(x, y)
+	}
+	return Decimal{}, unsupportedOperator(op, "decimals")
+}
+
+// quo divides x by y and rounds the result to the configured scale.
+func (e decimalEvaluator) quo(x, y Decimal) (Decimal, error) {
+	if y.int().Sign() == 0 {
+		return Decimal{}, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s / %s", x.short(), y)}
+	}
+
+	// x/y * 10^scale = a*10^-sa / (b*10^-sb) * 10^scale = a*10^(sb-sa+scale) / b
+	num, den := new(big.Int).Set(x.int()), new(big.Int).Set(y.int())
+	if exp := y.scale - x.scale + e.opts.Scale; exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+
+	res, err := roundQuo(num, den, e.opts.Rounding)
+	if err != nil {
+		return Decimal{}, &EvalError{
+			Kind: InexactResult,
+			Msg:  fmt.Sprintf("%s / %s cannot be represented with %d fraction digits", x.short(), y.short(), e.opts.Scale),
+			Err:  err,
+		}
+	}
+	return Decimal{unscaled: res, scale: e.opts.Scale}, nil
+}
+
+// maxPowDigits is the largest amount of fraction digits of a power x^n,
+// because they need a power of ten of the same size.
+const maxPowDigits = maxPowBits / 4
+
+// pow returns x^y for an integer exponent y.
+func (e decimalEvaluator) pow(x, y Decimal) (Decimal, error) {
+	exp, err := y.rescale(0, RoundExact)
+	if err != nil || !exp.int().IsInt64() {
+		return Decimal{}, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("%s ^ %s is not supported with decimals, the exponent must be an integer", x.short(), y.short())}
+	}
+
+	n := exp.int().Int64()
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	if powTooLarge(x.int(), n) || (x.scale > 0 && abs > int64(maxPowDigits/x.scale)) {
+		return Decimal{}, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("%s ^ %d is too large", x.short(), n)}
+	}
+	res := Decimal{
+		unscaled: new(big.Int).Exp(x.int(), big.NewInt(abs), nil),
+		scale:    x.scale * int(abs),
+	}
+	if n < 0 {
+		return e.quo(Decimal{unscaled: big.NewInt(1)}, res)
+	}
+	return res, nil
+}
+
+// roundQuo returns num / den rounded to an integer using the rounding mode.
+func roundQuo(num, den *big.Int, mode RoundingMode) (*big.Int, error) {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q, nil
+	}
+
+	// The truncated quotient q is rounded away from zero by adding sign.
+	sign := int64(num.Sign() * den.Sign())
+	away := false
+	switch mode {
+	case RoundExact:
+		return nil, errInexact
+	case RoundUp:
+		away = true
+	case RoundDown:
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	case RoundHalfEven, RoundHalfUp, RoundHalfDown:
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		switch half.CmpAbs(den) {
+		case 1:
+			away = true
+		case 0:
+			away = mode == RoundHalfUp || (mode == RoundHalfEven && q.Bit(0) == 1)
+		}
+	default:
+		return nil, fmt.Errorf("unknown rounding mode %d", mode)
+	}
+
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q, nil
+}
+
+var errInexact = errors.New("rounding would be necessary")
+
+// parseDecimal parses any number literal whose value has a finite decimal representation.
+func parseDecimal(s string) (Decimal, bool) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, false
+	}
+
+	// A fraction has a finite decimal representation if its denominator
+	// only has the prime factors 2 and 5.
+	den := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	for den.Bit(0) == 0 {
+		den.Rsh(den, 1)
+		twos++
+	}
+	five, mod := big.NewInt(5), new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, five, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den = q
+		fives++
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return Decimal{}, false
+	}
+
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	unscaled := new(big.Int).Mul(r.Num(), pow10(scale))
+	return Decimal{unscaled: unscaled.Quo(unscaled, r.Denom()), scale: scale}, true
+}
+
+func decimalCmp(x, y Decimal) int {
+	return x.Rat().Cmp(y.Rat())
+}
+
+func decimalSum(values []Decimal) Decimal {
+	res := Decimal{}
+	for _, v := range values {
+		res, _ = decimalEvaluator{}.binary("+", res, v)
+	}
+	return res
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,114 @@
+package calc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestSolveDecimal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     calc.DecimalOptions
+		want     string
+		wantKind calc.ErrorKind
+	}{
+		{name: "exact sum", input: "0.1+0.2", opts: calc.DecimalOptions{Scale: 2}, want: "0.30"},
+		{name: "integer", input: "42", want: "42"},
+		{name: "literal beyond float64", input: "1e400 / 4e399", opts: calc.DecimalOptions{Scale: 1}, want: "2.5"},
+		{name: "negative", input: "1.5-3", opts: calc.DecimalOptions{Scale: 2}, want: "-1.50"},
+		{name: "small value", input: "0.01*0.5", opts: calc.DecimalOptions{Scale: 3}, want: "0.005"},
+		{name: "exact division", input: "10/4", opts: calc.DecimalOptions{Scale: 2}, want: "2.50"},
+		{name: "inexact division", input: "10/3", opts: calc.DecimalOptions{Scale: 2}, wantKind: calc.InexactResult},
+		{name: "inexact result", input: "0.125*1", opts: calc.DecimalOptions{Scale: 2}, wantKind: calc.InexactResult},
+		{name: "half even down", input: "0.125*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, want: "0.12"},
+		{name: "half even up", input: "0.135*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, want: "0.14"},
+		{name: "half up", input: "0.125*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfUp}, want: "0.13"},
+		{name: "half up negative", input: "0-0.125", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfUp}, want: "-0.13"},
+		{name: "half down", input: "0.125*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfDown}, want: "0.12"},
+		{name: "up", input: "0.121*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundUp}, want: "0.13"},
+		{name: "down", input: "0.129*1", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundDown}, want: "0.12"},
+		{name: "ceiling negative", input: "0-0.129", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundCeiling}, want: "-0.12"},
+		{name: "floor negative", input: "0-0.121", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundFloor}, want: "-0.13"},
+		{name: "division uses scale", input: "1/3*3", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, want: "0.99"},
+		{name: "negative exponent", input: "2^-2", opts: calc.DecimalOptions{Scale: 2}, want: "0.25"},
+		{name: "power", input: "1.1^2", opts: calc.DecimalOptions{Scale: 2}, want: "1.21"},
+		{name: "round function", input: "ROUND(2.345, 2)", opts: calc.DecimalOptions{Scale: 2}, want: "2.35"},
+		{name: "round function half even", input: "ROUND(2.345, 2)", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, want: "2.34"},
+		{name: "round tens", input: "ROUND(1250, -2)", opts: calc.DecimalOptions{Rounding: calc.RoundHalfEven}, want: "1200"},
+		{name: "round tens above half", input: "ROUND(1250.7, -2)", opts: calc.DecimalOptions{Rounding: calc.RoundHalfEven}, want: "1300"},
+		{name: "average", input: "AVG(1, 2, 4)", opts: calc.DecimalOptions{Scale: 4, Rounding: calc.RoundHalfUp}, want: "2.3333"},
+		{name: "floor", input: "FLOOR(0-2.5)", want: "-3"},
+		{name: "clamp", input: "CLAMP(7.5, 0, 3.25)", opts: calc.DecimalOptions{Scale: 2}, want: "3.25"},
+		{name: "fractional exponent", input: "2^0.5", wantKind: calc.Unsupported},
+		{name: "one with huge exponent", input: "1^(10^10)", want: "1"},
+		{name: "huge power", input: "3^(10^10)", wantKind: calc.Overflow},
+		{name: "huge negative power", input: "1.5^-(10^10)", wantKind: calc.Overflow},
+		{name: "too many fraction digits", input: "0.1^(10^9)", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, wantKind: calc.Overflow},
+		{name: "too many fraction digits of negative power", input: "0.01^-(10^8)", opts: calc.DecimalOptions{Scale: 2, Rounding: calc.RoundHalfEven}, wantKind: calc.Overflow},
+		{name: "constant", input: "2*PI", wantKind: calc.Unsupported},
+		{name: "irrational function", input: "SQRT(4)", wantKind: calc.Unsupported},
+		{name: "division by zero", input: "1/(1-1)", wantKind: calc.DivisionByZero},
+		{name: "floor division", input: "10.5 // 3", want: "3"},
+		{name: "modulo", input: "10.5 % 3", opts: calc.DecimalOptions{Scale: 2}, want: "1.50"},
+		{name: "factorial", input: "5!", opts: calc.DecimalOptions{Scale: 1}, want: "120.0"},
+		{name: "factorial of fraction", input: "1.5!", wantKind: calc.Unsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.SolveDecimal(tt.input, tt.opts)
+			if tt.wantKind != 0 {
+				var evalErr *calc.EvalError
+				if !errors.As(err, &evalErr) || evalErr.Kind != tt.wantKind {
+					t.Errorf("SolveDecimal() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("SolveDecimal() error = %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("SolveDecimal() got = %v, want %v", got, tt.want)
+			}
+			if got.Scale() != tt.opts.Scale {
+				t.Errorf("SolveDecimal() scale = %v, want %v", got.Scale(), tt.opts.Scale)
+			}
+		})
+	}
+}
+
+func TestEvalDecimal(t *testing.T) {
+	tree, err := calc.ParseExpr("price*3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calc.EvalDecimal(tree, calc.Env{"PRICE": 0.1}, calc.DecimalOptions{Scale: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "0.30" {
+		t.Errorf("EvalDecimal() got = %v, want 0.30", got)
+	}
+	if got.Float64() != 0.3 {
+		t.Errorf("Float64() got = %v, want 0.3", got.Float64())
+	}
+
+	if _, err := calc.EvalDecimal(tree, nil, calc.DecimalOptions{Scale: -1}); err == nil {
+		t.Error("EvalDecimal() with negative scale returned no error")
+	}
+}
+
+func TestSolveDecimal_LongError(t *testing.T) {
+	_, err := calc.SolveDecimal("0.5^1000", calc.DecimalOptions{Scale: 2})
+	if err == nil {
+		t.Fatal("SolveDecimal() returned no error")
+	}
+
+	want := "result: 0.000000000000000000...05253696441650390625 (1002 digits) cannot be represented with 2 fraction digits: rounding would be necessary"
+	if err.Error() != want {
+		t.Errorf("SolveDecimal() error = %v, want %v", err, want)
+	}
+}
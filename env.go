@@ -0,0 +1,46 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Env maps variable names to their values.
+// The Scanner upper-cases all identifiers unless ScanOptions.CaseSensitive is
+// set, so by default the names have to be upper-case too.
+type Env map[string]float64
+
+// Lookup returns the value of a variable.
+// Variables shadow the built-in constants with the same name,
+// which are matched case-insensitively.
+func (e Env) Lookup(name string) (float64, error) {
+	if val, ok := e[name]; ok {
+		return val, nil
+	}
+	if val, ok := consts[strings.ToUpper(name)]; ok {
+		return val, nil
+	}
+	if hint := caseSuggestion(name, e.names()); hint != "" {
+		return 0, &EvalError{Kind: UnknownIdentifier, Msg: fmt.Sprintf("undefined variable %s%s", name, hint)}
+	}
+	return 0, &EvalError{Kind: UnknownIdentifier, Msg: fmt.Sprintf("undefined variable %s%s", name, suggestion(name, e.names(), Env(consts).names(), DefaultRegistry.Names()))}
+}
+
+// names returns the names of the variables.
+func (e Env) names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Constants returns a copy of the built-in constants, which can be used in
+// all expressions.
+func Constants() Env {
+	res := make(Env, len(consts))
+	for name, val := range consts {
+		res[name] = val
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestEnv_Lookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     calc.Env
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{name: "a variable", env: calc.Env{"X": 3}, input: "X", want: 3},
+		{name: "a constant", env: calc.Env{"X": 3}, input: "PI", want: 3.141592653589793},
+		{name: "a constant with nil env", env: nil, input: "E", want: 2.718281828459045},
+		{name: "a variable shadows a constant", env: calc.Env{"PI": 3}, input: "PI", want: 3},
+		{name: "undefined variable", env: calc.Env{"X": 3}, input: "Y", wantErr: true},
+		{name: "undefined variable with nil env", env: nil, input: "Y", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.env.Lookup(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnv_LookupSuggestion(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     calc.Env
+		input   string
+		wantErr string
+	}{
+		{name: "a constant", env: nil, input: "PY", wantErr: "undefined variable PY, did you mean PI?"},
+		{name: "a variable", env: calc.Env{"WIDTH": 3}, input: "WIDHT", wantErr: "undefined variable WIDHT, did you mean WIDTH?"},
+		{name: "a function", env: nil, input: "SQR", wantErr: "undefined variable SQR, did you mean SQRT?"},
+		{name: "several matches", env: calc.Env{"PHI2": 1}, input: "PHI1", wantErr: "undefined variable PHI1, did you mean PHI or PHI2?"},
+		{name: "nothing similar", env: calc.Env{"X": 3}, input: "Y", wantErr: "undefined variable Y"},
+		{name: "lower-case variable", env: calc.Env{"x": 1}, input: "X", wantErr: "undefined variable X, did you mean x? Variables have to be upper-case unless ScanOptions.CaseSensitive is set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.env.Lookup(tt.input)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConstants(t *testing.T) {
+	got := calc.Constants()
+	if got["PI"] != 3.141592653589793 {
+		t.Errorf("Constants()[PI] = %v, want 3.141592653589793", got["PI"])
+	}
+
+	// Changing the copy must not change the constants.
+	got["PI"] = 3
+	if val, _ := calc.Env(nil).Lookup("PI"); val != 3.141592653589793 {
+		t.Errorf("Lookup(PI) = %v after changing the copy", val)
+	}
+}
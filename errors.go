@@ -0,0 +1,262 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies the errors returned while parsing and evaluating expressions.
+type ErrorKind int
+
+// These constants are all possible ErrorKind values.
+const (
+	// InvalidToken is returned if the input contains a character which cannot start a token.
+	InvalidToken ErrorKind = iota + 1
+	// InvalidNumber is returned for malformed numbers such as "1.2.3".
+	InvalidNumber
+	// UnexpectedToken is returned if a token is not allowed at its position.
+	UnexpectedToken
+	// UnbalancedParen is returned if parentheses do not match.
+	UnbalancedParen
+	// MissingOperand is returned if an operator or a function lacks an operand.
+	MissingOperand
+	// EmptyExpression is returned if there is nothing to evaluate.
+	EmptyExpression
+	// UnknownOperator is returned for operators which do not exist.
+	UnknownOperator
+	// UnknownFunction is returned for calls of functions which do not exist.
+	UnknownFunction
+	// UnknownIdentifier is returned for variables and constants which do not exist.
+	UnknownIdentifier
+	// ArgumentCount is returned if a function is called with a wrong amount of arguments.
+	ArgumentCount
+	// DivisionByZero is returned if a value is divided by zero.
+	DivisionByZero
+	// DomainError is returned if an operation is not defined for its operands, such as SQRT(-1).
+	DomainError
+	// FunctionError is returned if a function itself returns an error.
+	FunctionError
+	// Unsupported is returned if an operation is not available in the used evaluation mode.
+	Unsupported
+	// InexactResult is returned if a decimal result would have to be rounded
+	// although rounding is not allowed.
+	InexactResult
+	// Overflow is returned if an integer result or a number literal does not
+	// fit into its type.
+	Overflow
+	// Canceled is returned if the context of SolveContext is done.
+	Canceled
+	// InputTooLong is returned if an expression exceeds SolveOptions.MaxLength.
+	InputTooLong
+	// TooManyTokens is returned if an expression exceeds SolveOptions.MaxTokens.
+	TooManyTokens
+	// TooDeep is returned if an expression exceeds SolveOptions.MaxDepth or
+	// if the calls of functions defined by a script exceed ScriptOptions.MaxCallDepth.
+	TooDeep
+	// TooManySteps is returned if an evaluation exceeds SolveOptions.MaxSteps.
+	TooManySteps
+)
+
+var errorKindNames = map[ErrorKind]string{
+	InvalidToken:      "invalid token",
+	InvalidNumber:     "invalid number",
+	UnexpectedToken:   "unexpected token",
+	UnbalancedParen:   "unbalanced parenthesis",
+	MissingOperand:    "missing operand",
+	EmptyExpression:   "empty expression",
+	UnknownOperator:   "unknown operator",
+	UnknownFunction:   "unknown function",
+	UnknownIdentifier: "unknown identifier",
+	ArgumentCount:     "wrong argument count",
+	DivisionByZero:    "division by zero",
+	DomainError:       "domain error",
+	FunctionError:     "function error",
+	Unsupported:       "unsupported",
+	InexactResult:     "inexact result",
+	Overflow:          "overflow",
+	Canceled:          "canceled",
+	InputTooLong:      "input too long",
+	TooManyTokens:     "too many tokens",
+	TooDeep:           "nesting too deep",
+	TooManySteps:      "too many steps",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// Position is a location in the input of an expression.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the position in the line counted in runes, starting at 1.
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SyntaxError is returned if an expression cannot be parsed.
+type SyntaxError struct {
+	Kind ErrorKind
+	// Pos is the position of the offending token.
+	// It may be invalid if the position is unknown.
+	Pos Position
+	// Token is the offending token, if there is one.
+	Token Token
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return formatError(e.Pos, e.Msg)
+}
+
+// EvalError is returned if a parsed expression cannot be evaluated.
+type EvalError struct {
+	Kind ErrorKind
+	// Pos is the position of the offending token.
+	// It may be invalid if the position is unknown.
+	Pos Position
+	// Token is the offending token, if there is one.
+	Token Token
+	Msg   string
+	// Err is the underlying error, if there is one.
+	Err error
+}
+
+func (e *EvalError) Error() string {
+	if e.Err != nil {
+		return formatError(e.Pos, e.Msg+": "+e.Err.Error())
+	}
+	return formatError(e.Pos, e.Msg)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// LimitError is returned if an expression exceeds one of the limits of
+// SolveOptions or ScriptOptions.
+type LimitError struct {
+	Kind ErrorKind
+	// Limit is the exceeded limit.
+	Limit int
+	// Pos is the position of the first token over the limit.
+	// It may be invalid if the position is unknown.
+	Pos Position
+	Msg string
+}
+
+func (e *LimitError) Error() string {
+	return formatError(e.Pos, e.Msg)
+}
+
+func formatError(pos Position, msg string) string {
+	if !pos.IsValid() {
+		return msg
+	}
+	return pos.String() + ": " + msg
+}
+
+// at sets the position of an *EvalError which has no position yet.
+func at(err error, pos Position) error {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) && !evalErr.Pos.IsValid() {
+		evalErr.Pos = pos
+	}
+	return err
+}
+
+// relocate sets the position of an *EvalError or *LimitError to pos.
+func relocate(err error, pos Position) error {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) {
+		evalErr.Pos = pos
+	}
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		limitErr.Pos = pos
+	}
+	return err
+}
+
+// ErrorPosition returns the position stored in a *SyntaxError, *EvalError or *LimitError.
+// The position is invalid if err contains none of them.
+func ErrorPosition(err error) Position {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Pos
+	}
+
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) {
+		return evalErr.Pos
+	}
+
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Pos
+	}
+
+	return Position{}
+}
+
+// Caret returns the line of the input which contains pos followed by a
+// second line with a caret under the column of pos.
+// Tabs are kept so that the caret lines up with the input.
+func Caret(input string, pos Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+
+	lines := strings.Split(input, "\n")
+	if pos.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimSuffix(lines[pos.Line-1], "\r")
+
+	var marker strings.Builder
+	column := 1
+	for _, ch := range line {
+		if column >= pos.Column {
+			break
+		}
+		if ch == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+		column++
+	}
+	for ; column < pos.Column; column++ {
+		marker.WriteRune(' ')
+	}
+	marker.WriteRune('^')
+
+	return line + "\n" + marker.String()
+}
+
+// FormatError returns the message of err and, if err has a position,
+// the offending line of the input with a caret under the error.
+func FormatError(input string, err error) string {
+	caret := Caret(input, ErrorPosition(err))
+	if caret == "" {
+		return err.Error()
+	}
+	return err.Error() + "\n" + caret
+}
@@ -0,0 +1,154 @@
+package calc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantKind calc.ErrorKind
+		wantEval bool
+	}{
+		{name: "invalid token", input: "1 + $", wantKind: calc.InvalidToken},
+		{name: "invalid number", input: "2.243.4*345", wantKind: calc.InvalidNumber},
+		{name: "number too large for float64", input: "1e400", wantKind: calc.Overflow, wantEval: true},
+		{name: "missing operator", input: "(1+2)(3+4)", wantKind: calc.UnexpectedToken},
+		{name: "comma outside of a function", input: "(1, 2)", wantKind: calc.UnexpectedToken},
+		{name: "missing closing parenthesis", input: "((2*(5+3)+4", wantKind: calc.UnbalancedParen},
+		{name: "missing opening parenthesis", input: "(2*(5+3))+4)", wantKind: calc.UnbalancedParen},
+		{name: "missing operand", input: "((2*(5+3))+4)+", wantKind: calc.MissingOperand},
+		{name: "missing operand after minus", input: "5-", wantKind: calc.MissingOperand},
+		{name: "missing argument after comma", input: "2 + MAX(1,)", wantKind: calc.MissingOperand},
+		{name: "missing argument between commas", input: "MAX(1,,2)", wantKind: calc.MissingOperand},
+		{name: "empty expression", input: "  ", wantKind: calc.EmptyExpression},
+		{name: "empty parentheses", input: "2 * ()", wantKind: calc.EmptyExpression},
+		{name: "unknown function", input: "LOOL(5)", wantKind: calc.UnknownFunction, wantEval: true},
+		{name: "unknown identifier", input: "2*LOOL", wantKind: calc.UnknownIdentifier, wantEval: true},
+		{name: "wrong argument count", input: "HYPOT(1)", wantKind: calc.ArgumentCount, wantEval: true},
+		{name: "division by zero", input: "1/(2-2)", wantKind: calc.DivisionByZero, wantEval: true},
+		{name: "domain error in function", input: "SQRT(-1)", wantKind: calc.DomainError, wantEval: true},
+		{name: "domain error in operator", input: "(-8)^0.5", wantKind: calc.DomainError, wantEval: true},
+		{name: "error of a function", input: "CLAMP(1, 2, 0)", wantKind: calc.FunctionError, wantEval: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := calc.Solve(tt.input)
+
+			var syntaxErr *calc.SyntaxError
+			var evalErr *calc.EvalError
+			switch {
+			case errors.As(err, &syntaxErr):
+				if tt.wantEval {
+					t.Errorf("Solve() error = %#v, want *EvalError", err)
+				} else if syntaxErr.Kind != tt.wantKind {
+					t.Errorf("Solve() error kind = %v, want %v", syntaxErr.Kind, tt.wantKind)
+				}
+			case errors.As(err, &evalErr):
+				if !tt.wantEval {
+					t.Errorf("Solve() error = %#v, want *SyntaxError", err)
+				} else if evalErr.Kind != tt.wantKind {
+					t.Errorf("Solve() error kind = %v, want %v", evalErr.Kind, tt.wantKind)
+				}
+			default:
+				t.Errorf("Solve() error = %#v, want %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  calc.Position
+	}{
+		{name: "first column", input: "$", want: calc.Position{Offset: 0, Line: 1, Column: 1}},
+		{name: "inside of a line", input: "1 + $", want: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "after multi byte runes", input: "1 +\u00a0\u00a0$", want: calc.Position{Offset: 7, Line: 1, Column: 6}},
+		{name: "second line", input: "1 +\n  2 + $", want: calc.Position{Offset: 10, Line: 2, Column: 7}},
+		{name: "missing operand", input: "2 * 3 +", want: calc.Position{Offset: 6, Line: 1, Column: 7}},
+		{name: "unclosed parenthesis", input: "1 + (2", want: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "empty parentheses", input: "1 + ( )", want: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "missing argument", input: "2 + MAX(1, )", want: calc.Position{Offset: 9, Line: 1, Column: 10}},
+		{name: "unknown identifier", input: "1 + LOOL", want: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "unknown function", input: "2 * LOOL(3)", want: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "division by zero", input: "1 + 1 / 0", want: calc.Position{Offset: 6, Line: 1, Column: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := calc.Solve(tt.input)
+			if got := calc.ErrorPosition(err); got != tt.want {
+				t.Errorf("ErrorPosition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaret(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   calc.Position
+		want  string
+	}{
+		{name: "invalid position", input: "1+2", pos: calc.Position{}, want: ""},
+		{name: "first column", input: "$+2", pos: calc.Position{Line: 1, Column: 1}, want: "$+2\n^"},
+		{name: "inside of a line", input: "1 + $", pos: calc.Position{Line: 1, Column: 5}, want: "1 + $\n    ^"},
+		{name: "with tabs", input: "\t1 +\t$", pos: calc.Position{Line: 1, Column: 6}, want: "\t1 +\t$\n\t   \t^"},
+		{name: "second line", input: "1 +\n2 + $", pos: calc.Position{Line: 2, Column: 5}, want: "2 + $\n    ^"},
+		{name: "end of the line", input: "1 +", pos: calc.Position{Line: 1, Column: 4}, want: "1 +\n   ^"},
+		{name: "line out of range", input: "1 +", pos: calc.Position{Line: 2, Column: 1}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calc.Caret(tt.input, tt.pos); got != tt.want {
+				t.Errorf("Caret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	input := "1 + $"
+	_, err := calc.Solve(input)
+
+	want := "1:5: invalid token '$'\n1 + $\n    ^"
+	if got := calc.FormatError(input, err); got != want {
+		t.Errorf("FormatError() = %q, want %q", got, want)
+	}
+
+	err = errors.New("some error")
+	if got := calc.FormatError(input, err); got != "some error" {
+		t.Errorf("FormatError() = %q, want %q", got, "some error")
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "1 + 1e400", want: "1:5: number 1e400 is too large for float64"},
+		{input: "2 + MAX(1,)", want: "1:10: missing argument after ','"},
+		{input: "5 / 0", want: "1:3: division by zero: 5 / 0"},
+		{input: "5 // 0", want: "1:3: division by zero: 5 // 0"},
+		{input: "5 % 0", want: "1:3: division by zero: 5 % 0"},
+		{input: "1 << 1000", want: "1:3: integer overflow: 1 << 1000"},
+		{input: "1 << 53", want: "1:3: integer overflow: 1 << 53"},
+		{input: "2^63 & 1", want: "1:6: operator & needs integers between -2^53 and 2^53, got 9.223372036854776e+18"},
+		{input: "9007199254740993 & 1", want: "1:18: operator & needs integers between -2^53 and 2^53, got 9.007199254740992e+15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := calc.Solve(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Solve() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
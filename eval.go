@@ -0,0 +1,260 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Eval evaluates the abstract syntax tree of an expression.
+func Eval(n Node) (float64, error) {
+	return EvalWith(n, nil)
+}
+
+// EvalWith evaluates the abstract syntax tree of an expression.
+// Identifiers are resolved using vars.
+func EvalWith(n Node, vars Env) (float64, error) {
+	return (&evaluator{vars: vars}).eval(n)
+}
+
+// evaluator evaluates an abstract syntax tree using float64 values.
+type evaluator struct {
+	vars Env
+
+	// ctx, maxSteps, lenient and registry are only set by SolveContext.
+	ctx      context.Context
+	maxSteps int
+	steps    int
+	// lenient evaluates unknown identifiers to 0, see SolveOptions.Lenient.
+	lenient bool
+	// registry contains the callable functions, nil means DefaultRegistry.
+	registry *Registry
+
+	// funcs are the functions defined by a script, which are called
+	// instead of the functions of the registry with the same name.
+	funcs map[string]*userFunc
+	// callDepth is the amount of active calls of funcs.
+	callDepth    int
+	maxCallDepth int
+	// run identifies the call of Script.Eval, see userFunc.run.
+	run int
+}
+
+// step counts the evaluation of a node and checks the limits set by SolveContext.
+func (e *evaluator) step() error {
+	e.steps++
+	if e.maxSteps > 0 && e.steps > e.maxSteps {
+		return &LimitError{Kind: TooManySteps, Limit: e.maxSteps, Msg: fmt.Sprintf("evaluation needs more than %d steps", e.maxSteps)}
+	}
+	if e.ctx != nil {
+		return contextError(e.ctx)
+	}
+	return nil
+}
+
+// contextError returns an error with the kind Canceled if ctx is done.
+func contextError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &EvalError{Kind: Canceled, Msg: "evaluation canceled", Err: err}
+	}
+	return nil
+}
+
+func (e *evaluator) eval(n Node) (float64, error) {
+	if err := e.step(); err != nil {
+		return 0, err
+	}
+
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return 0, imaginaryUnsupported(n, "float64")
+		}
+		if n.overflows() {
+			return 0, literalOverflow(n, "float64")
+		}
+		return n.Value, nil
+	case *ConstRef:
+		val, err := e.vars.Lookup(n.Name)
+		var evalErr *EvalError
+		if e.lenient && errors.As(err, &evalErr) && evalErr.Kind == UnknownIdentifier {
+			return 0, nil
+		}
+		return val, at(err, n.Pos)
+	case *UnaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			return -x, nil
+		case "!":
+			return boolToFloat(x == 0), nil
+		case "~":
+			res, err := bitNot(x)
+			return res, at(err, n.Pos)
+		}
+		return 0, at(unknownOperator(n.Op), n.Pos)
+	case *BinaryOp:
+		opr, ok := oprData[n.Op]
+		if !ok || opr.fx == nil {
+			return 0, at(unknownOperator(n.Op), n.Pos)
+		}
+
+		x, err := e.eval(n.X)
+		if err != nil {
+			return 0, err
+		}
+
+		// The second operand of a logical operator is only evaluated if it matters.
+		switch {
+		case n.Op == "&&" && x == 0:
+			return 0, nil
+		case n.Op == "||" && x != 0:
+			return 1, nil
+		}
+
+		y, err := e.eval(n.Y)
+		if err != nil {
+			return 0, err
+		}
+		res, err := binary(n.Op, opr.fx, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		c, err := e.eval(n.Cond)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return e.eval(n.Then)
+		}
+		return e.eval(n.Else)
+	case *Call:
+		args := make([]float64, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.eval(arg)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = val
+		}
+		if f, ok := e.funcs[n.Name]; ok {
+			res, err := e.call(n, f, args)
+			return res, at(err, n.Pos)
+		}
+		res, err := e.registry.orDefault().Call(n.Name, args...)
+		return res, at(err, n.Pos)
+	}
+
+	return 0, fmt.Errorf("unsupported node %T", n)
+}
+
+// call calls a function defined by a script.
+// Its body is evaluated with the variables from the definition and the arguments.
+func (e *evaluator) call(n *Call, f *userFunc, args []float64) (float64, error) {
+	if err := (arity{len(f.params), len(f.params)}).checkArity(n.Name, len(args)); err != nil {
+		return 0, err
+	}
+	if e.callDepth >= e.maxCallDepth {
+		return 0, &LimitError{Kind: TooDeep, Limit: e.maxCallDepth, Pos: n.Pos, Msg: fmt.Sprintf("calls of %s are nested deeper than %d levels", n.Name, e.maxCallDepth)}
+	}
+
+	vars := make(Env, len(f.env)+len(args))
+	for name, val := range f.env {
+		vars[name] = val
+	}
+	for i, param := range f.params {
+		vars[param] = args[i]
+	}
+
+	outer := e.vars
+	e.vars = vars
+	e.callDepth++
+	res, err := e.eval(f.body)
+	e.vars = outer
+	e.callDepth--
+	if err != nil && f.run != e.run {
+		// The body was read by an earlier call of Script.Eval, so the
+		// error is reported at the call instead.
+		err = relocate(err, n.Pos)
+	}
+	return res, err
+}
+
+// binary applies a binary operator and reports division by zero and
+// results which are not defined for the operands.
+func binary(op string, fx func(x, y float64) float64, x, y float64) (float64, error) {
+	if (op == "/" || op == "//" || op == "%") && y == 0 {
+		return 0, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %v %s %v", x, op, y)}
+	}
+	if bitwiseOps[op] {
+		if err := checkInteger(op, x, y); err != nil {
+			return 0, err
+		}
+		if (op == "<<" || op == ">>") && y < 0 {
+			return 0, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("negative shift count: %v %s %v", x, op, y)}
+		}
+	}
+
+	res := fx(x, y)
+	if op == "<<" && math.Abs(res) >= maxExactInt {
+		return 0, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("integer overflow: %v << %v", x, y)}
+	}
+	if math.IsNaN(res) && !math.IsNaN(x) && !math.IsNaN(y) {
+		return 0, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%v %s %v is not defined", x, op, y)}
+	}
+	return res, nil
+}
+
+// bitwiseOps are the binary operators which need integer operands.
+var bitwiseOps = map[string]bool{
+	"&":   true,
+	"|":   true,
+	"XOR": true,
+	"<<":  true,
+	">>":  true,
+}
+
+// maxExactInt is 2^53. The integers between -maxExactInt and maxExactInt
+// are exactly representable as float64, so a literal such as
+// 9007199254740993, which is rounded to 2^53, is outside of this range.
+const maxExactInt = 1 << 53
+
+// checkInteger returns an error if one of the values is not an integer
+// between -maxExactInt and maxExactInt.
+func checkInteger(op string, values ...float64) error {
+	for _, v := range values {
+		if v != math.Trunc(v) || math.Abs(v) >= maxExactInt {
+			return &EvalError{Kind: DomainError, Msg: fmt.Sprintf("operator %s needs integers between -2^53 and 2^53, got %v", op, v)}
+		}
+	}
+	return nil
+}
+
+// bitNot returns the bitwise complement ~x of an integer.
+func bitNot(x float64) (float64, error) {
+	if err := checkInteger("~", x); err != nil {
+		return 0, err
+	}
+	return float64(^int64(x)), nil
+}
+
+func unknownOperator(op string) error {
+	return &EvalError{Kind: UnknownOperator, Msg: fmt.Sprintf("operator does not exist: %s", op)}
+}
+
+// unsupportedOperator returns an Unsupported error for existing operators
+// which the evaluation mode cannot handle.
+func unsupportedOperator(op, mode string) error {
+	_, isBinary := oprData[op]
+	_, isUnary := unaryPrec[op]
+	if !isBinary && !isUnary {
+		return unknownOperator(op)
+	}
+	return &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("operator %s is not supported with %s", op, mode)}
+}
@@ -0,0 +1,65 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   calc.Node
+		want    float64
+		wantErr bool
+	}{
+		{name: "a number", input: &calc.NumberLit{Value: 42}, want: 42},
+		{name: "a constant", input: &calc.ConstRef{Name: "PI"}, want: 3.141592653589793},
+		{name: "unknown constant", input: &calc.ConstRef{Name: "LOOL"}, wantErr: true},
+		{name: "unary minus", input: &calc.UnaryOp{Op: "-", X: &calc.NumberLit{Value: 2}}, want: -2},
+		{name: "unary plus", input: &calc.UnaryOp{Op: "+", X: &calc.NumberLit{Value: 2}}, want: 2},
+		{name: "unknown unary operator", input: &calc.UnaryOp{Op: "?", X: &calc.NumberLit{Value: 2}}, wantErr: true},
+		{
+			name:  "binary operator",
+			input: &calc.BinaryOp{Op: "/", X: &calc.NumberLit{Value: 9}, Y: &calc.NumberLit{Value: 2}},
+			want:  4.5,
+		},
+		{
+			name:    "unknown binary operator",
+			input:   &calc.BinaryOp{Op: "?", X: &calc.NumberLit{Value: 9}, Y: &calc.NumberLit{Value: 2}},
+			wantErr: true,
+		},
+		{
+			name:  "function call",
+			input: &calc.Call{Name: "SQRT", Args: []calc.Node{&calc.NumberLit{Value: 16}}},
+			want:  4,
+		},
+		{
+			name:    "unknown function",
+			input:   &calc.Call{Name: "LOOL", Args: []calc.Node{&calc.NumberLit{Value: 16}}},
+			wantErr: true,
+		},
+		{
+			name:    "function with wrong argument count",
+			input:   &calc.Call{Name: "SQRT"},
+			wantErr: true,
+		},
+		{
+			name:    "error in operand",
+			input:   &calc.BinaryOp{Op: "+", X: &calc.NumberLit{Value: 1}, Y: &calc.ConstRef{Name: "LOOL"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Eval(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Eval() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
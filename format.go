@@ -0,0 +1,320 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Notation selects how Format writes a number.
+type Notation int
+
+// These constants are all possible Notation values.
+const (
+	// Shortest uses the fewest digits which still represent the value exactly
+	// and an exponent only for very large and small values, e.g. 0.375,
+	// 1234567 or 1e+21.
+	Shortest Notation = iota
+	// Fixed uses Precision digits after the decimal point, e.g. 3.14.
+	Fixed
+	// Significant rounds to Precision significant digits and uses an
+	// exponent for large and small values, e.g. 3.14 or 1.23e+21.
+	Significant
+	// Engineering uses an exponent which is a multiple of three, e.g. 12.3e3.
+	Engineering
+	// SI uses an SI prefix instead of the exponent, e.g. 1.2k or 3.4µ.
+	SI
+	// Fraction writes the nearest fraction whose denominator is at most
+	// MaxDenominator, e.g. 3/8. It is found with continued fractions.
+	Fraction
+	// Hex writes integers in hexadecimal, e.g. 0xff.
+	Hex
+	// Binary writes integers in binary, e.g. 0b101.
+	Binary
+)
+
+// DefaultMaxDenominator is the largest denominator of Fraction if
+// FormatOptions.MaxDenominator is zero.
+const DefaultMaxDenominator = 1000000
+
+var notationNames = map[Notation]string{
+	Shortest:    "shortest",
+	Fixed:       "fixed",
+	Significant: "significant",
+	Engineering: "engineering",
+	SI:          "si",
+	Fraction:    "fraction",
+	Hex:         "hex",
+	Binary:      "binary",
+}
+
+func (n Notation) String() string {
+	if name, ok := notationNames[n]; ok {
+		return name
+	}
+	return fmt.Sprintf("Notation(%d)", int(n))
+}
+
+// ParseNotation returns the Notation with the given name, such as "fixed" or "si".
+func ParseNotation(name string) (Notation, error) {
+	for n, text := range notationNames {
+		if strings.EqualFold(name, text) {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown notation %s", name)
+}
+
+// FormatOptions configures Format.
+type FormatOptions struct {
+	Notation Notation
+
+	// Precision is the number of digits after the decimal point for Fixed and
+	// the number of significant digits for Significant, Engineering and SI.
+	// A negative precision uses as many digits as needed to represent the
+	// value exactly, which is also the case for zero with the notations
+	// based on significant digits.
+	Precision int
+
+	// Decimal is the decimal separator, which is "." if it is zero.
+	Decimal rune
+	// Group separates groups of three digits in the integer part, such as
+	// the "," in 1,234.5. Zero disables the grouping.
+	// For example Decimal ',' and Group '.' formats the German 1.234,5.
+	Group rune
+
+	// MaxDenominator is the largest denominator of Fraction.
+	// Zero means DefaultMaxDenominator.
+	MaxDenominator int64
+}
+
+// Validate returns an error if the separators cannot be told apart from
+// each other or from the digits and signs of a number.
+func (o FormatOptions) Validate() error {
+	decimal := o.Decimal
+	if decimal == 0 {
+		decimal = '.'
+	}
+	for _, sep := range []struct {
+		name string
+		ch   rune
+	}{{"decimal", decimal}, {"group", o.Group}} {
+		if unicode.IsDigit(sep.ch) || sep.ch == '-' || sep.ch == '+' {
+			return fmt.Errorf("invalid %s separator %q", sep.name, sep.ch)
+		}
+	}
+	if o.Group == decimal {
+		return fmt.Errorf("the decimal and the group separator are both %q", decimal)
+	}
+	return nil
+}
+
+// siPrefixes are the SI prefixes from 1e-30 to 1e30 in steps of 1e3.
+var siPrefixes = []string{"q", "r", "y", "z", "a", "f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E", "Z", "Y", "R", "Q"}
+
+// Format formats the result of an evaluation using opts.
+// Fraction, Hex and Binary return an *EvalError with the kind DomainError
+// for values they cannot represent, such as 1.5 in hexadecimal or 1e-9 as a
+// fraction with the default MaxDenominator. Invalid options return the
+// error of FormatOptions.Validate.
+func Format(v float64, opts FormatOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		switch opts.Notation {
+		case Fraction, Hex, Binary:
+			return "", &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%s output needs a finite number, got %v", opts.Notation, v)}
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+
+	switch opts.Notation {
+	case Shortest:
+		if abs := math.Abs(v); v == 0 || (abs >= 1e-4 && abs < 1e21) {
+			return localize(strconv.FormatFloat(v, 'f', -1, 64), opts), nil
+		}
+		return localize(strconv.FormatFloat(v, 'g', -1, 64), opts), nil
+	case Fixed:
+		prec := opts.Precision
+		if prec < 0 {
+			prec = -1
+		}
+		return localize(strconv.FormatFloat(v, 'f', prec, 64), opts), nil
+	case Significant:
+		return localize(strconv.FormatFloat(v, 'g', significantDigits(opts.Precision), 64), opts), nil
+	case Engineering:
+		mantissa, exp := engineering(v, opts.Precision)
+		if exp == 0 {
+			return localize(mantissa, opts), nil
+		}
+		return localize(mantissa, opts) + "e" + strconv.Itoa(exp), nil
+	case SI:
+		mantissa, exp := engineering(v, opts.Precision)
+		i := exp/3 + len(siPrefixes)/2
+		if i < 0 || i >= len(siPrefixes) {
+			return localize(mantissa, opts) + "e" + strconv.Itoa(exp), nil
+		}
+		return localize(mantissa, opts) + siPrefixes[i], nil
+	case Fraction:
+		maxDenom := opts.MaxDenominator
+		if maxDenom <= 0 {
+			maxDenom = DefaultMaxDenominator
+		}
+		r := limitDenominator(new(big.Rat).SetFloat64(v), big.NewInt(maxDenom))
+		if r.Sign() == 0 && v != 0 {
+			return "", &EvalError{Kind: DomainError, Msg: fmt.Sprintf("fraction output needs a denominator larger than %d for %v", maxDenom, v)}
+		}
+		if r.IsInt() {
+			return localize(r.Num().String(), opts), nil
+		}
+		return localize(r.Num().String(), opts) + "/" + localize(r.Denom().String(), opts), nil
+	case Hex, Binary:
+		if v != math.Trunc(v) {
+			return "", &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%s output needs an integer, got %v", opts.Notation, v)}
+		}
+		i, _ := big.NewFloat(v).Int(nil)
+		sign := ""
+		if i.Sign() < 0 {
+			sign = "-"
+			i.Neg(i)
+		}
+		if opts.Notation == Hex {
+			return sign + "0x" + i.Text(16), nil
+		}
+		return sign + "0b" + i.Text(2), nil
+	}
+	return "", &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("unknown notation %v", opts.Notation)}
+}
+
+// significantDigits converts FormatOptions.Precision into the precision of strconv.FormatFloat.
+func significantDigits(prec int) int {
+	if prec <= 0 {
+		return -1
+	}
+	return prec
+}
+
+// engineering returns the mantissa and the exponent of v rounded to prec
+// significant digits, where the exponent is a multiple of three.
+func engineering(v float64, prec int) (mantissa string, exp int) {
+	prec = significantDigits(prec)
+	if prec > 0 {
+		// The 'e' format counts the digits after the decimal point.
+		prec--
+	}
+
+	// The rounded value in scientific notation, e.g. -1.2345e+04.
+	s := strconv.FormatFloat(math.Abs(v), 'e', prec, 64)
+	e := strings.IndexByte(s, 'e')
+	exp, _ = strconv.Atoi(s[e+1:])
+	digits := strings.Replace(s[:e], ".", "", 1)
+
+	// Move the decimal point to the right until the exponent is a multiple of three.
+	shift := exp % 3
+	if shift < 0 {
+		shift += 3
+	}
+	exp -= shift
+	for len(digits) < shift+1 {
+		digits += "0"
+	}
+
+	mantissa = digits[:shift+1]
+	if len(digits) > shift+1 {
+		mantissa += "." + digits[shift+1:]
+	}
+	if math.Signbit(v) {
+		mantissa = "-" + mantissa
+	}
+	return mantissa, exp
+}
+
+// limitDenominator returns the fraction closest to r whose denominator is at
+// most maxDenom, using the convergents of the continued fraction of r.
+func limitDenominator(r *big.Rat, maxDenom *big.Int) *big.Rat {
+	if r.Denom().Cmp(maxDenom) <= 0 {
+		return r
+	}
+
+	// p0/q0 and p1/q1 are the last two convergents of |r| = n/d.
+	p0, q0, p1, q1 := big.NewInt(0), big.NewInt(1), big.NewInt(1), big.NewInt(0)
+	n, d := new(big.Int).Abs(r.Num()), new(big.Int).Set(r.Denom())
+	a, tmp := new(big.Int), new(big.Int)
+	for {
+		a.Quo(n, d)
+		q2 := new(big.Int).Add(q0, tmp.Mul(a, q1))
+		if q2.Cmp(maxDenom) > 0 {
+			break
+		}
+		p2 := new(big.Int).Add(p0, tmp.Mul(a, p1))
+		p0, q0, p1, q1 = p1, q1, p2, q2
+		n, d = d, n.Sub(n, tmp.Mul(a, d))
+	}
+
+	// The best approximation is either the last convergent or the
+	// semiconvergent with the largest allowed denominator.
+	k := new(big.Int).Quo(tmp.Sub(maxDenom, q0), q1)
+	semi := new(big.Rat).SetFrac(
+		new(big.Int).Add(p0, new(big.Int).Mul(k, p1)),
+		new(big.Int).Add(q0, new(big.Int).Mul(k, q1)),
+	)
+	conv := new(big.Rat).SetFrac(p1, q1)
+
+	abs := new(big.Rat).Abs(r)
+	res := conv
+	if ratDistance(semi, abs).Cmp(ratDistance(conv, abs)) < 0 {
+		res = semi
+	}
+	if r.Sign() < 0 {
+		res.Neg(res)
+	}
+	return res
+}
+
+// ratDistance returns |x - y|.
+func ratDistance(x, y *big.Rat) *big.Rat {
+	d := new(big.Rat).Sub(x, y)
+	return d.Abs(d)
+}
+
+// localize replaces the decimal point of a formatted number by opts.Decimal
+// and groups the digits of its integer part by opts.Group.
+// A number which is rounded to zero, such as -0.00, loses its sign.
+func localize(s string, opts FormatOptions) string {
+	start := 0
+	if strings.HasPrefix(s, "-") {
+		start = 1
+		mantissa := s
+		if e := strings.IndexByte(s, 'e'); e >= 0 {
+			mantissa = s[:e]
+		}
+		if strings.Trim(mantissa, "-0.") == "" {
+			s, start = s[1:], 0
+		}
+	}
+	end := start
+	for end < len(s) && isASCIIDigit(rune(s[end])) {
+		end++
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:start])
+	for i := start; i < end; i++ {
+		if opts.Group != 0 && i > start && (end-i)%3 == 0 {
+			b.WriteRune(opts.Group)
+		}
+		b.WriteByte(s[i])
+	}
+
+	rest := s[end:]
+	if opts.Decimal != 0 && strings.HasPrefix(rest, ".") {
+		b.WriteRune(opts.Decimal)
+		rest = rest[1:]
+	}
+	b.WriteString(rest)
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       float64
+		opts    calc.FormatOptions
+		want    string
+		wantErr string
+	}{
+		{name: "shortest", v: 0.375, want: "0.375"},
+		{name: "shortest large", v: 1e21, want: "1e+21"},
+		{name: "shortest without exponent", v: 1234567, want: "1234567"},
+		{name: "shortest small", v: 0.00001, want: "1e-05"},
+		{name: "shortest inf", v: math.Inf(1), want: "+Inf"},
+		{name: "shortest nan", v: math.NaN(), opts: calc.FormatOptions{Notation: calc.Fixed}, want: "NaN"},
+		{name: "fixed", v: math.Pi, opts: calc.FormatOptions{Notation: calc.Fixed, Precision: 2}, want: "3.14"},
+		{name: "fixed rounds", v: 2.5, opts: calc.FormatOptions{Notation: calc.Fixed}, want: "2"},
+		{name: "fixed pads", v: 1.5, opts: calc.FormatOptions{Notation: calc.Fixed, Precision: 3}, want: "1.500"},
+		{name: "fixed rounds to zero", v: -0.000123, opts: calc.FormatOptions{Notation: calc.Fixed}, want: "0"},
+		{name: "fixed rounds to zero with decimals", v: -0.000123, opts: calc.FormatOptions{Notation: calc.Fixed, Precision: 2, Decimal: ','}, want: "0,00"},
+		{name: "fixed negative zero", v: math.Copysign(0, -1), opts: calc.FormatOptions{Notation: calc.Fixed, Precision: 1}, want: "0.0"},
+		{name: "fixed negative precision", v: 1.25, opts: calc.FormatOptions{Notation: calc.Fixed, Precision: -1}, want: "1.25"},
+		{name: "significant", v: math.Pi, opts: calc.FormatOptions{Notation: calc.Significant, Precision: 3}, want: "3.14"},
+		{name: "significant exponent", v: 1234567, opts: calc.FormatOptions{Notation: calc.Significant, Precision: 2}, want: "1.2e+06"},
+		{name: "significant zero precision", v: 0.1, opts: calc.FormatOptions{Notation: calc.Significant}, want: "0.1"},
+		{name: "engineering", v: 12345, opts: calc.FormatOptions{Notation: calc.Engineering, Precision: 3}, want: "12.3e3"},
+		{name: "engineering pads", v: 12345, opts: calc.FormatOptions{Notation: calc.Engineering, Precision: 1}, want: "10e3"},
+		{name: "engineering small", v: -0.00042, opts: calc.FormatOptions{Notation: calc.Engineering}, want: "-420e-6"},
+		{name: "engineering without exponent", v: 123, opts: calc.FormatOptions{Notation: calc.Engineering}, want: "123"},
+		{name: "engineering rounds up", v: 999.96, opts: calc.FormatOptions{Notation: calc.Engineering, Precision: 3}, want: "1.00e3"},
+		{name: "engineering zero", v: 0, opts: calc.FormatOptions{Notation: calc.Engineering}, want: "0"},
+		{name: "si kilo", v: 1200, opts: calc.FormatOptions{Notation: calc.SI}, want: "1.2k"},
+		{name: "si micro", v: 3.4e-6, opts: calc.FormatOptions{Notation: calc.SI}, want: "3.4µ"},
+		{name: "si precision", v: 4.7e9, opts: calc.FormatOptions{Notation: calc.SI, Precision: 3}, want: "4.70G"},
+		{name: "si without prefix", v: 12, opts: calc.FormatOptions{Notation: calc.SI}, want: "12"},
+		{name: "si out of range", v: 1e33, opts: calc.FormatOptions{Notation: calc.SI}, want: "1e33"},
+		{name: "si decimal", v: 1500, opts: calc.FormatOptions{Notation: calc.SI, Decimal: ','}, want: "1,5k"},
+		{name: "group", v: 1234567.5, opts: calc.FormatOptions{Group: ','}, want: "1,234,567.5"},
+		{name: "group negative", v: -123456, opts: calc.FormatOptions{Group: ','}, want: "-123,456"},
+		{name: "group short", v: 123, opts: calc.FormatOptions{Group: ','}, want: "123"},
+		{name: "german", v: 1234.5, opts: calc.FormatOptions{Notation: calc.Fixed, Precision: 2, Decimal: ',', Group: '.'}, want: "1.234,50"},
+		{name: "narrow space", v: 1234567, opts: calc.FormatOptions{Group: ' '}, want: "1 234 567"},
+		{name: "fraction", v: 0.375, opts: calc.FormatOptions{Notation: calc.Fraction}, want: "3/8"},
+		{name: "fraction of rounded value", v: 1.0 / 3, opts: calc.FormatOptions{Notation: calc.Fraction}, want: "1/3"},
+		{name: "fraction negative", v: -2.75, opts: calc.FormatOptions{Notation: calc.Fraction}, want: "-11/4"},
+		{name: "fraction integer", v: 42, opts: calc.FormatOptions{Notation: calc.Fraction}, want: "42"},
+		{name: "fraction max denominator", v: math.Pi, opts: calc.FormatOptions{Notation: calc.Fraction, MaxDenominator: 100}, want: "311/99"},
+		{name: "fraction convergent", v: math.Pi, opts: calc.FormatOptions{Notation: calc.Fraction, MaxDenominator: 10}, want: "22/7"},
+		{name: "fraction grouped", v: 1234.5, opts: calc.FormatOptions{Notation: calc.Fraction, Group: ','}, want: "2,469/2"},
+		{name: "fraction inf", v: math.Inf(-1), opts: calc.FormatOptions{Notation: calc.Fraction}, wantErr: "fraction output needs a finite number, got -Inf"},
+		{name: "fraction too small", v: 1e-30, opts: calc.FormatOptions{Notation: calc.Fraction}, wantErr: "fraction output needs a denominator larger than 1000000 for 1e-30"},
+		{name: "fraction below max denominator", v: 2.5e-7, opts: calc.FormatOptions{Notation: calc.Fraction}, wantErr: "fraction output needs a denominator larger than 1000000 for 2.5e-07"},
+		{name: "fraction zero", v: 0, opts: calc.FormatOptions{Notation: calc.Fraction}, want: "0"},
+		{name: "hex", v: 255, opts: calc.FormatOptions{Notation: calc.Hex}, want: "0xff"},
+		{name: "hex negative", v: -16, opts: calc.FormatOptions{Notation: calc.Hex}, want: "-0x10"},
+		{name: "hex large", v: 1 << 62, opts: calc.FormatOptions{Notation: calc.Hex}, want: "0x4000000000000000"},
+		{name: "binary", v: 5, opts: calc.FormatOptions{Notation: calc.Binary}, want: "0b101"},
+		{name: "binary zero", v: 0, opts: calc.FormatOptions{Notation: calc.Binary}, want: "0b0"},
+		{name: "hex of fraction", v: 1.5, opts: calc.FormatOptions{Notation: calc.Hex}, wantErr: "hex output needs an integer, got 1.5"},
+		{name: "unknown notation", v: 1, opts: calc.FormatOptions{Notation: 100}, wantErr: "unknown notation Notation(100)"},
+		{name: "same separators", v: 1, opts: calc.FormatOptions{Decimal: ',', Group: ','}, wantErr: "the decimal and the group separator are both ','"},
+		{name: "group is default decimal", v: 1, opts: calc.FormatOptions{Group: '.'}, wantErr: "the decimal and the group separator are both '.'"},
+		{name: "digit as group", v: 1, opts: calc.FormatOptions{Group: '0'}, wantErr: "invalid group separator '0'"},
+		{name: "minus as decimal", v: 1, opts: calc.FormatOptions{Decimal: '-'}, wantErr: "invalid decimal separator '-'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Format(tt.v, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Format() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	for _, name := range []string{"shortest", "fixed", "significant", "engineering", "si", "fraction", "hex", "binary"} {
+		n, err := calc.ParseNotation(name)
+		if err != nil {
+			t.Fatalf("ParseNotation(%q) error = %v", name, err)
+		}
+		if n.String() != name {
+			t.Errorf("ParseNotation(%q) = %v", name, n)
+		}
+	}
+
+	if n, err := calc.ParseNotation("SI"); err != nil || n != calc.SI {
+		t.Errorf("ParseNotation(%q) = %v, %v, want %v", "SI", n, err, calc.SI)
+	}
+	if _, err := calc.ParseNotation("roman"); err == nil {
+		t.Errorf("ParseNotation(%q) expected an error", "roman")
+	}
+}
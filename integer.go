@@ -0,0 +1,381 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// IntDivision defines how "/" is evaluated in the integer evaluation modes.
+type IntDivision int
+
+// These constants are all possible IntDivision values.
+const (
+	// IntDivTruncate divides and truncates the result toward zero, like Go does.
+	IntDivTruncate IntDivision = iota
+	// IntDivExact divides and returns an error if there is a remainder.
+	IntDivExact
+	// IntDivReject does not allow "/" at all.
+	IntDivReject
+)
+
+// IntOptions configure the integer evaluation modes.
+type IntOptions struct {
+	// Division defines how "/" is evaluated.
+	Division IntDivision
+}
+
+var (
+	minInt64 = big.NewInt(math.MinInt64)
+	maxInt64 = big.NewInt(math.MaxInt64)
+)
+
+// maxFactorial is the largest n whose factorial the exact evaluation modes
+// calculate, larger ones would take too long.
+const maxFactorial = 100000
+
+// maxShift is the largest amount of bits a big.Int is shifted to the left.
+const maxShift = 1 << 20
+
+// maxPowBits is the largest amount of bits of a power x^n which the exact
+// evaluation modes calculate, larger ones would take too long.
+const maxPowBits = maxShift
+
+// powTooLarge reports whether |x|^|n| has more than maxPowBits bits.
+func powTooLarge(x *big.Int, n int64) bool {
+	if n < 0 {
+		n = -n
+	}
+	// |x| has bits-1 bits after its leading one, so x^n has at least n times as many.
+	bits := int64(x.BitLen()) - 1
+	return bits > 0 && (n < 0 || n > maxPowBits/bits)
+}
+
+// SolveInt solves a mathematical calculation using int64 arithmetic.
+func SolveInt(s string, opts IntOptions) (int64, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return EvalInt(tree, nil, opts)
+}
+
+// EvalInt evaluates the abstract syntax tree of an expression using int64
+// arithmetic. Every intermediate result which does not fit into an int64
+// returns an error of the kind Overflow instead of wrapping around.
+//
+// See EvalBigInt for the supported operations.
+func EvalInt(n Node, vars Env, opts IntOptions) (int64, error) {
+	e := intEvaluator{vars: vars, opts: opts, int64: true}
+	res, err := e.eval(n)
+	if err != nil {
+		return 0, err
+	}
+	return res.Int64(), nil
+}
+
+// SolveBigInt solves a mathematical calculation using big.Int arithmetic.
+func SolveBigInt(s string, opts IntOptions) (*big.Int, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return EvalBigInt(tree, nil, opts)
+}
+
+// EvalBigInt evaluates the abstract syntax tree of an expression using
+// big.Int arithmetic, so the results are always exact.
+//
+// Number literals must be integers, "/" is evaluated as configured by
+// opts.Division, "//" and "%" always round toward negative infinity and "^"
+// needs a non-negative exponent. The bitwise operators work like on integers
+// in two's complement with infinitely many bits. The built-in constants are not available and
+// only the built-in functions ABS, MIN, MAX, SUM, POW, FACT and CLAMP can be
+// called. Variables must have integer values.
+func EvalBigInt(n Node, vars Env, opts IntOptions) (*big.Int, error) {
+	e := intEvaluator{vars: vars, opts: opts}
+	return e.eval(n)
+}
+
+type intFunc struct {
+	arity
+	fn func(e intEvaluator, args []*big.Int) (*big.Int, error)
+}
+
+var intFuncs = map[string]intFunc{
+	"ABS": {arity{1, 1}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		return e.check(new(big.Int).Abs(args[0]), "ABS(%s)", args[0])
+	}},
+	"MIN": {arity{1, Variadic}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) < 0 {
+				res = v
+			}
+		}
+		return res, nil
+	}},
+	"MAX": {arity{1, Variadic}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			if v.Cmp(res) > 0 {
+				res = v
+			}
+		}
+		return res, nil
+	}},
+	"SUM": {arity{1, Variadic}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		res := new(big.Int)
+		for _, v := range args {
+			var err error
+			if res, err = e.binary("+", res, v); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}},
+	"POW": {arity{2, 2}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		return e.binary("^", args[0], args[1])
+	}},
+	"FACT": {arity{1, 1}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		res, err := intFactorial(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return e.check(res, "%s!", args[0])
+	}},
+	"CLAMP": {arity{3, 3}, func(e intEvaluator, args []*big.Int) (*big.Int, error) {
+		if args[1].Cmp(args[2]) > 0 {
+			return nil, &EvalError{Kind: FunctionError, Msg: "CLAMP", Err: fmt.Errorf("lower bound %s is greater than upper bound %s", args[1], args[2])}
+		}
+		res := args[0]
+		if res.Cmp(args[1]) < 0 {
+			res = args[1]
+		} else if res.Cmp(args[2]) > 0 {
+			res = args[2]
+		}
+		return res, nil
+	}},
+}
+
+type intEvaluator struct {
+	vars Env
+	opts IntOptions
+	// int64 limits all results to the range of int64.
+	int64 bool
+}
+
+func (e intEvaluator) mode() string {
+	if e.int64 {
+		return "int64"
+	}
+	return "big.Int"
+}
+
+func (e intEvaluator) eval(n Node) (*big.Int, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return nil, imaginaryUnsupported(n, e.mode())
+		}
+		text := n.Text
+		if text == "" {
+			text = fmt.Sprint(n.Value)
+		}
+		res, ok := new(big.Int).SetString(text, 10)
+		if !ok && strings.ContainsAny(text, "eE") {
+			// A literal with an exponent such as 1e3 may still be an integer.
+			if r, isRat := new(big.Rat).SetString(text); isRat && r.IsInt() {
+				res, ok = r.Num(), true
+			}
+		}
+		if !ok {
+			return nil, &EvalError{Kind: InvalidNumber, Pos: n.Pos, Msg: fmt.Sprintf("%s is not an integer, float literals are not allowed with %s", text, e.mode())}
+		}
+		return e.check(res, "%s", text)
+	case *ConstRef:
+		if val, ok := e.vars[n.Name]; ok {
+			if math.IsNaN(val) || math.IsInf(val, 0) || val != math.Trunc(val) {
+				return nil, &EvalError{Kind: DomainError, Pos: n.Pos, Msg: fmt.Sprintf("variable %s is not an integer: %v", n.Name, val)}
+			}
+			res, _ := big.NewFloat(val).Int(nil)
+			return e.check(res, "%s", n.Name)
+		}
+		if _, ok := consts[strings.ToUpper(n.Name)]; ok {
+			return nil, &EvalError{Kind: Unsupported, Pos: n.Pos, Msg: fmt.Sprintf("constant %s is not supported with %s", n.Name, e.mode())}
+		}
+		_, err := e.vars.Lookup(n.Name)
+		return nil, at(err, n.Pos)
+	case *UnaryOp:
+		// A negated literal is checked as a whole, because the absolute value
+		// of the minimal int64 does not fit into an int64.
+		if lit, ok := n.X.(*NumberLit); ok && n.Op == "-" && !lit.Imag && lit.Text != "" {
+			return e.eval(&NumberLit{Pos: lit.Pos, Value: -lit.Value, Text: "-" + lit.Text})
+		}
+
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Op {
+		case "+":
+			return x, nil
+		case "-":
+			res, err := e.check(new(big.Int).Neg(x), "-%s", x)
+			return res, at(err, n.Pos)
+		case "~":
+			return new(big.Int).Not(x), nil
+		}
+		return nil, at(unsupportedOperator(n.Op, e.mode()), n.Pos)
+	case *BinaryOp:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := e.eval(n.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := e.binary(n.Op, x, y)
+		return res, at(err, n.Pos)
+	case *Cond:
+		return nil, at(unsupportedOperator("?:", e.mode()), n.Pos)
+	case *Call:
+		f, ok := intFuncs[n.Name]
+		if !ok {
+			return nil, at(unsupportedFunction(n.Name, e.mode()), n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return nil, at(err, n.Pos)
+		}
+
+		args := make([]*big.Int, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+
+		res, err := f.fn(e, args)
+		return res, at(err, n.Pos)
+	}
+
+	return nil, fmt.Errorf("unsupported node %T", n)
+}
+
+func (e intEvaluator) binary(op string, x, y *big.Int) (*big.Int, error) {
+	res := new(big.Int)
+	switch op {
+	case "+":
+		res.Add(x, y)
+	case "-":
+		res.Sub(x, y)
+	case "*":
+		res.Mul(x, y)
+	case "/":
+		if e.opts.Division == IntDivReject {
+			return nil, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("division is not allowed: %s / %s", x, y)}
+		}
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s / %s", x, y)}
+		}
+
+		rem := new(big.Int)
+		res.QuoRem(x, y, rem)
+		if e.opts.Division == IntDivExact && rem.Sign() != 0 {
+			return nil, &EvalError{Kind: InexactResult, Msg: fmt.Sprintf("%s / %s has the remainder %s", x, y, rem)}
+		}
+	case "//", "%":
+		if y.Sign() == 0 {
+			return nil, &EvalError{Kind: DivisionByZero, Msg: fmt.Sprintf("division by zero: %s %s %s", x, op, y)}
+		}
+
+		quo, mod := floorDivMod(x, y)
+		if op == "//" {
+			res = quo
+		} else {
+			res = mod
+		}
+	case "&":
+		res.And(x, y)
+	case "|":
+		res.Or(x, y)
+	case "XOR":
+		res.Xor(x, y)
+	case "<<", ">>":
+		if y.Sign() < 0 {
+			return nil, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("negative shift count: %s %s %s", x, op, y)}
+		}
+
+		// Shifting right by more than the length of x is the same as by its length.
+		n := uint(x.BitLen())
+		if y.IsUint64() && y.Uint64() < uint64(n) {
+			n = uint(y.Uint64())
+		}
+		if op == ">>" {
+			res.Rsh(x, n)
+			break
+		}
+
+		if x.Sign() != 0 && (!y.IsInt64() || y.Int64() > maxShift || (e.int64 && y.Int64() >= 64)) {
+			return nil, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("integer overflow: %s << %s", x, y)}
+		}
+		res.Lsh(x, uint(y.Uint64()))
+	case "^":
+		if y.Sign() < 0 {
+			return nil, &EvalError{Kind: Unsupported, Msg: fmt.Sprintf("%s ^ %s is not supported with %s, the exponent must not be negative", x, y, e.mode())}
+		}
+
+		// Only 0, 1 and -1 stay small with large exponents.
+		if x.CmpAbs(big.NewInt(1)) > 0 && (!y.IsInt64() || (e.int64 && y.Int64() >= 64) || powTooLarge(x, y.Int64())) {
+			return nil, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("integer overflow: %s ^ %s", x, y)}
+		}
+		if !y.IsInt64() {
+			// The exponent only matters for the sign of -1.
+			y = new(big.Int).And(y, big.NewInt(1))
+		}
+		res.Exp(x, y, nil)
+	default:
+		return nil, unsupportedOperator(op, e.mode())
+	}
+
+	return e.check(res, "%s %s %s", x, op, y)
+}
+
+// floorDivMod returns the quotient of x / y rounded toward negative infinity
+// and the matching remainder, which has the sign of y.
+func floorDivMod(x, y *big.Int) (quo, mod *big.Int) {
+	quo, mod = new(big.Int).QuoRem(x, y, new(big.Int))
+	if mod.Sign() != 0 && mod.Sign() != y.Sign() {
+		quo.Sub(quo, big.NewInt(1))
+		mod.Add(mod, y)
+	}
+	return quo, mod
+}
+
+// intFactorial returns n! for a non-negative n.
+func intFactorial(n *big.Int) (*big.Int, error) {
+	if n.Sign() < 0 {
+		return nil, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("FACT is not defined for %s", n)}
+	}
+	if !n.IsInt64() || n.Int64() > maxFactorial {
+		return nil, &EvalError{Kind: Overflow, Msg: fmt.Sprintf("factorial of %s is too large", n)}
+	}
+	return new(big.Int).MulRange(1, n.Int64()), nil
+}
+
+// check returns an Overflow error if res does not fit into the range of the evaluation mode.
+// The format and args describe the operation which lead to res.
+func (e intEvaluator) check(res *big.Int, format string, args ...interface{}) (*big.Int, error) {
+	if e.int64 && (res.Cmp(minInt64) < 0 || res.Cmp(maxInt64) > 0) {
+		return nil, &EvalError{Kind: Overflow, Msg: "integer overflow: " + fmt.Sprintf(format, args...)}
+	}
+	return res, nil
+}
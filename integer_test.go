@@ -0,0 +1,143 @@
+package calc_test
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestSolveInt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     calc.IntOptions
+		want     int64
+		wantKind calc.ErrorKind
+		wantPos  calc.Position
+	}{
+		{name: "simple", input: "((2*(5+3))+4)*(300/100)", want: 60},
+		{name: "above 2^53", input: "2^53+1", want: 9007199254740993},
+		{name: "max int64", input: "2^62-1+2^62", want: 9223372036854775807},
+		{name: "min int64", input: "-9223372036854775808", want: -9223372036854775808},
+		{name: "truncating division", input: "7/2", want: 3},
+		{name: "truncating negative division", input: "-7/2", want: -3},
+		{name: "exact division", input: "8/2", opts: calc.IntOptions{Division: calc.IntDivExact}, want: 4},
+		{name: "inexact division", input: "7/2", opts: calc.IntOptions{Division: calc.IntDivExact}, wantKind: calc.InexactResult},
+		{name: "rejected division", input: "8/2", opts: calc.IntOptions{Division: calc.IntDivReject}, wantKind: calc.Unsupported},
+		{name: "functions", input: "MAX(1, SUM(2, 3), ABS(-4))", want: 5},
+		{name: "power of one", input: "1^100000000000", want: 1},
+		{name: "power of minus one", input: "(0-1)^100000000001", want: -1},
+		{name: "addition overflow", input: "9223372036854775807+1", wantKind: calc.Overflow, wantPos: calc.Position{Offset: 19, Line: 1, Column: 20}},
+		{name: "multiplication overflow", input: "2^32*2^32", wantKind: calc.Overflow},
+		{name: "power overflow", input: "2^63", wantKind: calc.Overflow},
+		{name: "large power overflow", input: "3^1000000", wantKind: calc.Overflow},
+		{name: "literal overflow", input: "9223372036854775808", wantKind: calc.Overflow},
+		{name: "division overflow", input: "-9223372036854775808/(0-1)", wantKind: calc.Overflow},
+		{name: "float literal", input: "1 + 1.5", wantKind: calc.InvalidNumber, wantPos: calc.Position{Offset: 4, Line: 1, Column: 5}},
+		{name: "negative exponent", input: "2^-1", wantKind: calc.Unsupported},
+		{name: "constant", input: "PI", wantKind: calc.Unsupported},
+		{name: "float function", input: "SQRT(4)", wantKind: calc.Unsupported},
+		{name: "division by zero", input: "1/0", wantKind: calc.DivisionByZero},
+		{name: "floor division", input: "-7 // 2", want: -4},
+		{name: "floor division with rejected division", input: "7 // 2", opts: calc.IntOptions{Division: calc.IntDivReject}, want: 3},
+		{name: "modulo", input: "-7 % 3", want: 2},
+		{name: "modulo by zero", input: "7 % 0", wantKind: calc.DivisionByZero},
+		{name: "bitwise operators", input: "0xF0 & 0x3C | 0x01 xor 0x03", want: 0x32},
+		{name: "bitwise not", input: "~0b101", want: -6},
+		{name: "shift left", input: "1 << 62", want: 1 << 62},
+		{name: "shift right negative", input: "-5 >> 1", want: -3},
+		{name: "shift right by a lot", input: "-5 >> 1000", want: -1},
+		{name: "shift overflow", input: "1 << 63", wantKind: calc.Overflow},
+		{name: "large shift overflow", input: "1 << 64", wantKind: calc.Overflow},
+		{name: "negative shift", input: "1 << -1", wantKind: calc.DomainError},
+		{name: "exponent literal", input: "2e3 + 1.5E1", want: 2015},
+		{name: "fractional exponent literal", input: "1.55e1", wantKind: calc.InvalidNumber},
+		{name: "hex literal", input: "0x7FFF_FFFF_FFFF_FFFF", want: 9223372036854775807},
+		{name: "factorial", input: "20!", want: 2432902008176640000},
+		{name: "factorial overflow", input: "21!", wantKind: calc.Overflow},
+		{name: "factorial of negative", input: "(-1)!", wantKind: calc.DomainError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.SolveInt(tt.input, tt.opts)
+			if tt.wantKind != 0 {
+				var evalErr *calc.EvalError
+				if !errors.As(err, &evalErr) || evalErr.Kind != tt.wantKind {
+					t.Fatalf("SolveInt() error = %v, want %v", err, tt.wantKind)
+				}
+				if tt.wantPos.IsValid() && evalErr.Pos != tt.wantPos {
+					t.Errorf("SolveInt() error position = %+v, want %+v", evalErr.Pos, tt.wantPos)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("SolveInt() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("SolveInt() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveBigInt(t *testing.T) {
+	got, err := calc.SolveBigInt("2^100+1", calc.IntOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := new(big.Int).SetString("1267650600228229401496703205377", 10)
+	if got.Cmp(want) != 0 {
+		t.Errorf("SolveBigInt() got = %v, want %v", got, want)
+	}
+
+	literal := "1" + strings.Repeat("0", 400)
+	got, err = calc.SolveBigInt(literal+"+1", calc.IntOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1" + strings.Repeat("0", 399) + "1"; got.String() != want {
+		t.Errorf("SolveBigInt() got = %v, want %v", got, want)
+	}
+}
+
+func TestSolveBigInt_PowerLimit(t *testing.T) {
+	if _, err := calc.SolveBigInt("2^1048576", calc.IntOptions{}); err != nil {
+		t.Errorf("SolveBigInt() error = %v", err)
+	}
+	if got, err := calc.SolveBigInt("(-1)^(10^30+1)", calc.IntOptions{}); err != nil || got.Int64() != -1 {
+		t.Errorf("SolveBigInt() = %v, %v, want -1", got, err)
+	}
+
+	for _, input := range []string{"3^(10^10)", "2^1048577"} {
+		_, err := calc.SolveBigInt(input, calc.IntOptions{})
+		var evalErr *calc.EvalError
+		if !errors.As(err, &evalErr) || evalErr.Kind != calc.Overflow {
+			t.Errorf("SolveBigInt(%q) error = %v, want %v", input, err, calc.Overflow)
+		}
+	}
+}
+
+func TestEvalInt(t *testing.T) {
+	tree, err := calc.ParseExpr("size*1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calc.EvalInt(tree, calc.Env{"SIZE": 4}, calc.IntOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 4096 {
+		t.Errorf("EvalInt() got = %v, want 4096", got)
+	}
+
+	_, err = calc.EvalInt(tree, calc.Env{"SIZE": 0.5}, calc.IntOptions{})
+	var evalErr *calc.EvalError
+	if !errors.As(err, &evalErr) || evalErr.Kind != calc.DomainError {
+		t.Errorf("EvalInt() error = %v, want %v", err, calc.DomainError)
+	}
+}
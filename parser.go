@@ -2,7 +2,9 @@ package calc
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 )
 
 type tokenBuffer struct {
@@ -18,12 +20,33 @@ type TokenScanner interface {
 }
 
 type Parser struct {
-	s   TokenScanner
-	buf tokenBuffer
+	s    TokenScanner
+	buf  tokenBuffer
+	opts ParseOptions
+	// registry decides about calls with implicit multiplication, see isCall.
+	// Nil means DefaultRegistry.
+	registry *Registry
+}
+
+// ParseOptions configures a Parser.
+type ParseOptions struct {
+	// Scan configures the scanner which reads the tokens.
+	Scan ScanOptions
+
+	// ImplicitMul allows to leave out the "*" between two operands, such as in
+	// 2PI, 3(4+5), (1+2)(3+4) and 2SIN(x).
+	// The implicit multiplication has the same precedence as "*", so 1/2x is
+	// (1/2)*x and 2x^2 is 2*(x^2). Two numbers such as 2 3 still need an operator.
+	ImplicitMul bool
 }
 
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: NewScanner(r)}
+	return NewParserOptions(r, ParseOptions{})
+}
+
+// NewParserOptions returns a Parser which is configured by opts.
+func NewParserOptions(r io.Reader, opts ParseOptions) *Parser {
+	return &Parser{s: NewScannerOptions(r, opts.Scan), opts: opts}
 }
 
 func (p *Parser) Scan() (Token, error) {
@@ -66,22 +89,78 @@ func (p *Parser) Parse() (Stack, error) {
 			break
 		} else if err != nil {
 			return Stack{}, err
-		} else if tok.Type == Operator && tok.Value == "-" {
-			lastTok := stack.Peek()
-			nextTok, err := p.ScanIgnoreWhitespace()
-			if err != nil {
-				return Stack{}, err
+		}
+
+		if tok.Type == Lparen && stack.Peek().Type == Constant && p.isCall(stack.Peek(), tok) {
+			stack[len(stack)-1].Type = Function
+			stack[len(stack)-1].Value = strings.ToUpper(stack.Peek().Value)
+		}
+
+		if startsOperand(tok) && !stack.IsEmpty() && endsOperand(stack.Peek()) {
+			if !p.opts.ImplicitMul || (tok.Type == Number && stack.Peek().Type == Number) {
+				return Stack{}, &SyntaxError{Kind: UnexpectedToken, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("missing operator before %s", tok.Value)}
 			}
+			stack.Push(Token{Type: Operator, Value: "*", Pos: tok.Pos, End: tok.Pos})
+		}
 
-			if (lastTok.Type == Operator || lastTok.Value == "" || lastTok.Type == Lparen) && nextTok.Type == Number {
-				stack.Push(Token{Number, "-" + nextTok.Value})
-			} else {
-				stack.Push(tok)
-				p.Unscan()
+		if tok.Type == Operator && isUnaryPosition(stack) {
+			if _, ok := unaryPrec[tok.Value]; !ok {
+				return Stack{}, &SyntaxError{Kind: MissingOperand, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("missing operand for '%s'", tok.Value)}
 			}
+			tok.Type = UnaryOperator
+			stack.Push(tok)
+		} else if _, ok := postfixFuncs[tok.Value]; tok.Type == Operator && ok {
+			tok.Type = PostfixOperator
+			stack.Push(tok)
+		} else if _, ok := oprData[tok.Value]; tok.Type == Operator && !ok && tok.Value != ":" {
+			return Stack{}, &SyntaxError{Kind: UnexpectedToken, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("unexpected operator %s", tok.Value)}
 		} else {
 			stack.Push(tok)
 		}
 	}
 	return stack, nil
 }
+
+// isCall reports whether the identifier name, which is separated by spaces
+// from the parenthesis lparen, is called such as in COS (0). A line break
+// ends the call. With implicit multiplication only functions are called, so
+// PI (2) is PI * 2.
+func (p *Parser) isCall(name, lparen Token) bool {
+	if name.End.Line != lparen.Pos.Line {
+		return false
+	}
+	return !p.opts.ImplicitMul || p.registry.orDefault().has(strings.ToUpper(name.Value))
+}
+
+// startsOperand reports whether tok is the first token of an operand.
+func startsOperand(tok Token) bool {
+	switch tok.Type {
+	case Number, Constant, Function, Lparen:
+		return true
+	}
+	return false
+}
+
+// endsOperand reports whether tok is the last token of an operand.
+func endsOperand(tok Token) bool {
+	switch tok.Type {
+	case Number, Constant, Rparen, PostfixOperator:
+		return true
+	}
+	return false
+}
+
+// isUnaryPosition reports whether an operator following the tokens is a unary operator,
+// which is the case if there is no operand in front of it.
+func isUnaryPosition(tokens Stack) bool {
+	if tokens.IsEmpty() {
+		return true
+	}
+
+	switch tokens.Peek().Type {
+	case Operator, UnaryOperator, Lparen, Comma:
+		// A postfix operator is an operand itself, so it is not in this list.
+		return true
+	}
+	return false
+}
@@ -53,21 +53,53 @@ func newFakeScanner(tokens tokenOrErrStack) *fakeScanner {
 var (
 	testTokensNormal = tokenOrErrStack{
 		{token: Token{Type: Number, Value: "42"}},
-		{token: Token{Type: Constant, Value: "PI"}},
+		{token: Token{Type: Operator, Value: "+"}},
 		{token: Token{Type: Function, Value: "COS"}},
 		{err: io.EOF},
 	}
 
 	testTokensWithWhiteSpace = tokenOrErrStack{
 		{token: Token{Type: Number, Value: "42"}},
-		{token: Token{Type: Constant, Value: "PI"}},
+		{token: Token{Type: Operator, Value: "+"}},
 		{token: Token{Type: Whitespace, Value: " "}},
 		{token: Token{Type: Function, Value: "COS"}},
 		{err: io.EOF},
 	}
 
+	testTokensWithUnary = tokenOrErrStack{
+		{token: Token{Type: Operator, Value: "-"}},
+		{token: Token{Type: Number, Value: "2"}},
+		{token: Token{Type: Operator, Value: "-"}},
+		{token: Token{Type: Lparen, Value: "("}},
+		{token: Token{Type: Operator, Value: "+"}},
+		{token: Token{Type: Constant, Value: "PI"}},
+		{token: Token{Type: Rparen, Value: ")"}},
+		{token: Token{Type: Operator, Value: "!"}},
+		{token: Token{Type: Operator, Value: "*"}},
+		{token: Token{Type: Operator, Value: "!"}},
+		{token: Token{Type: Constant, Value: "X"}},
+		{err: io.EOF},
+	}
+
 	testTokensEmpty = tokenOrErrStack{{err: io.EOF}}
 
+	testTokensWithoutOperator = tokenOrErrStack{
+		{token: Token{Type: Number, Value: "2"}},
+		{token: Token{Type: Constant, Value: "X"}},
+		{token: Token{Type: Rparen, Value: ")"}},
+		{token: Token{Type: Lparen, Value: "("}},
+		{token: Token{Type: Operator, Value: "!"}},
+		{token: Token{Type: Function, Value: "SIN"}},
+		{err: io.EOF},
+	}
+
+	testTokensTwoNumbers = tokenOrErrStack{
+		{token: Token{Type: Number, Value: "2"}},
+		{token: Token{Type: Whitespace, Value: " "}},
+		{token: Token{Type: Number, Value: "3"}},
+		{err: io.EOF},
+	}
+
 	testTokensWithError = tokenOrErrStack{
 		{token: Token{Type: Number, Value: "42"}},
 		{token: Token{Type: Constant, Value: "PI"}},
@@ -79,8 +111,9 @@ var (
 
 func TestParser_Parse(t *testing.T) {
 	type fields struct {
-		s   TokenScanner
-		buf tokenBuffer
+		s    TokenScanner
+		buf  tokenBuffer
+		opts ParseOptions
 	}
 	tests := []struct {
 		name    string
@@ -120,12 +153,68 @@ func TestParser_Parse(t *testing.T) {
 			want:    testTokensNormal.toStack(),
 			wantErr: false,
 		},
+		{
+			name: "with unary operators",
+			fields: fields{
+				s: newFakeScanner(testTokensWithUnary),
+			},
+			want: Stack{
+				{Type: UnaryOperator, Value: "-"},
+				{Type: Number, Value: "2"},
+				{Type: Operator, Value: "-"},
+				{Type: Lparen, Value: "("},
+				{Type: UnaryOperator, Value: "+"},
+				{Type: Constant, Value: "PI"},
+				{Type: Rparen, Value: ")"},
+				{Type: PostfixOperator, Value: "!"},
+				{Type: Operator, Value: "*"},
+				{Type: UnaryOperator, Value: "!"},
+				{Type: Constant, Value: "X"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "two operands without operator",
+			fields: fields{
+				s: newFakeScanner(testTokensWithoutOperator),
+			},
+			want:    Stack{},
+			wantErr: true,
+		},
+		{
+			name: "implicit multiplication",
+			fields: fields{
+				s:    newFakeScanner(testTokensWithoutOperator),
+				opts: ParseOptions{ImplicitMul: true},
+			},
+			want: Stack{
+				{Type: Number, Value: "2"},
+				{Type: Operator, Value: "*"},
+				{Type: Constant, Value: "X"},
+				{Type: Rparen, Value: ")"},
+				{Type: Operator, Value: "*"},
+				{Type: Lparen, Value: "("},
+				{Type: UnaryOperator, Value: "!"},
+				{Type: Function, Value: "SIN"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "implicit multiplication of two numbers",
+			fields: fields{
+				s:    newFakeScanner(testTokensTwoNumbers),
+				opts: ParseOptions{ImplicitMul: true},
+			},
+			want:    Stack{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Parser{
-				s:   tt.fields.s,
-				buf: tt.fields.buf,
+				s:    tt.fields.s,
+				buf:  tt.fields.buf,
+				opts: tt.fields.opts,
 			}
 			got, err := p.Parse()
 			if (err != nil) != tt.wantErr {
@@ -0,0 +1,262 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opcode int
+
+const (
+	opPush opcode = iota
+	opLoad
+	opNeg
+	opNot
+	opBitNot
+	opBool
+	opBinary
+	opCall
+	opJump
+	opJumpIfZero
+	opJumpIfNotZero
+)
+
+// instr is a single instruction of a Program.
+type instr struct {
+	op opcode
+
+	// value is pushed by opPush.
+	value float64
+	// name is the variable loaded by opLoad, the operator of opBinary or
+	// the function called by opCall.
+	name string
+	// argc is the amount of arguments popped by opCall.
+	argc int
+	// target is the index of the next instruction after a jump.
+	// The conditional jumps pop the value they check.
+	target int
+	// pos is the position used for errors.
+	pos Position
+
+	fx2 func(x, y float64) float64
+	fn  funcEntry
+}
+
+// Program is a compiled expression.
+// Parsing and converting the expression happens only once in Compile, so
+// a Program can be evaluated many times at low cost.
+type Program struct {
+	instrs []instr
+	depth  int
+	// registry resolves the functions while compiling.
+	registry *Registry
+}
+
+// ProgramOptions configures CompileOptions.
+type ProgramOptions struct {
+	// Parse configures the parser.
+	Parse ParseOptions
+	// Registry contains the functions which can be called.
+	// If it is nil, the DefaultRegistry is used.
+	Registry *Registry
+}
+
+// Compile parses an expression and prepares it for evaluation.
+// Functions are resolved using the DefaultRegistry at compile time.
+func Compile(expr string) (*Program, error) {
+	return CompileOptions(expr, ProgramOptions{})
+}
+
+// CompileOptions compiles an expression like Compile using the configuration opts.
+func CompileOptions(expr string, opts ProgramOptions) (*Program, error) {
+	parser := NewParserOptions(strings.NewReader(expr), opts.Parse)
+	parser.registry = opts.Registry
+	tree, err := parse(parser)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Program{registry: opts.Registry.orDefault()}
+	if err := p.compile(tree, 0); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compile appends the instructions of the node to the program.
+// depth is the amount of values already on the stack when the node is evaluated.
+func (p *Program) compile(n Node, depth int) error {
+	if depth+1 > p.depth {
+		p.depth = depth + 1
+	}
+
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Imag {
+			return imaginaryUnsupported(n, "float64")
+		}
+		if n.overflows() {
+			return literalOverflow(n, "float64")
+		}
+		p.instrs = append(p.instrs, instr{op: opPush, value: n.Value})
+	case *ConstRef:
+		p.instrs = append(p.instrs, instr{op: opLoad, name: n.Name, pos: n.Pos})
+	case *UnaryOp:
+		if err := p.compile(n.X, depth); err != nil {
+			return err
+		}
+
+		switch n.Op {
+		case "+":
+		case "-":
+			p.instrs = append(p.instrs, instr{op: opNeg})
+		case "!":
+			p.instrs = append(p.instrs, instr{op: opNot})
+		case "~":
+			p.instrs = append(p.instrs, instr{op: opBitNot, pos: n.Pos})
+		default:
+			return at(unknownOperator(n.Op), n.Pos)
+		}
+	case *BinaryOp:
+		opr, ok := oprData[n.Op]
+		if !ok || opr.fx == nil {
+			return at(unknownOperator(n.Op), n.Pos)
+		}
+
+		if n.Op == "&&" || n.Op == "||" {
+			return p.compileLogical(n, depth)
+		}
+
+		if err := p.compile(n.X, depth); err != nil {
+			return err
+		}
+		if err := p.compile(n.Y, depth+1); err != nil {
+			return err
+		}
+		p.instrs = append(p.instrs, instr{op: opBinary, name: n.Op, fx2: opr.fx, pos: n.Pos})
+	case *Cond:
+		if err := p.compile(n.Cond, depth); err != nil {
+			return err
+		}
+		toElse := p.emitJump(opJumpIfZero)
+		if err := p.compile(n.Then, depth); err != nil {
+			return err
+		}
+		toEnd := p.emitJump(opJump)
+		p.patchJump(toElse)
+		if err := p.compile(n.Else, depth); err != nil {
+			return err
+		}
+		p.patchJump(toEnd)
+	case *Call:
+		f, err := p.registry.lookup(n.Name)
+		if err != nil {
+			return at(err, n.Pos)
+		}
+		if err := f.checkArity(n.Name, len(n.Args)); err != nil {
+			return at(err, n.Pos)
+		}
+
+		for i, arg := range n.Args {
+			if err := p.compile(arg, depth+i); err != nil {
+				return err
+			}
+		}
+		p.instrs = append(p.instrs, instr{op: opCall, name: n.Name, argc: len(n.Args), fn: f, pos: n.Pos})
+	default:
+		return fmt.Errorf("unsupported node %T", n)
+	}
+
+	return nil
+}
+
+// compileLogical compiles "&&" and "||" so that the second operand is
+// skipped if the first one already decides the result.
+func (p *Program) compileLogical(n *BinaryOp, depth int) error {
+	jump, short := opJumpIfZero, 0.0
+	if n.Op == "||" {
+		jump, short = opJumpIfNotZero, 1
+	}
+
+	if err := p.compile(n.X, depth); err != nil {
+		return err
+	}
+	toShort := p.emitJump(jump)
+	if err := p.compile(n.Y, depth); err != nil {
+		return err
+	}
+	p.instrs = append(p.instrs, instr{op: opBool})
+	toEnd := p.emitJump(opJump)
+	p.patchJump(toShort)
+	p.instrs = append(p.instrs, instr{op: opPush, value: short})
+	p.patchJump(toEnd)
+	return nil
+}
+
+// emitJump appends a jump and returns its index so that its target can be set by patchJump.
+func (p *Program) emitJump(op opcode) int {
+	p.instrs = append(p.instrs, instr{op: op})
+	return len(p.instrs) - 1
+}
+
+// patchJump lets the jump at index i continue after the last instruction.
+func (p *Program) patchJump(i int) {
+	p.instrs[i].target = len(p.instrs)
+}
+
+// Eval evaluates the program.
+// Identifiers are resolved using vars.
+func (p *Program) Eval(vars Env) (float64, error) {
+	stack := make([]float64, 0, p.depth)
+	for pc := 0; pc < len(p.instrs); {
+		in := &p.instrs[pc]
+		pc++
+
+		switch in.op {
+		case opPush:
+			stack = append(stack, in.value)
+		case opLoad:
+			val, err := vars.Lookup(in.name)
+			if err != nil {
+				return 0, at(err, in.pos)
+			}
+			stack = append(stack, val)
+		case opNeg:
+			stack[len(stack)-1] = -stack[len(stack)-1]
+		case opNot:
+			stack[len(stack)-1] = boolToFloat(stack[len(stack)-1] == 0)
+		case opBitNot:
+			res, err := bitNot(stack[len(stack)-1])
+			if err != nil {
+				return 0, at(err, in.pos)
+			}
+			stack[len(stack)-1] = res
+		case opBool:
+			stack[len(stack)-1] = boolToFloat(stack[len(stack)-1] != 0)
+		case opBinary:
+			y := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			res, err := binary(in.name, in.fx2, stack[len(stack)-1], y)
+			if err != nil {
+				return 0, at(err, in.pos)
+			}
+			stack[len(stack)-1] = res
+		case opCall:
+			res, err := in.fn.call(in.name, stack[len(stack)-in.argc:])
+			if err != nil {
+				return 0, at(err, in.pos)
+			}
+			stack = append(stack[:len(stack)-in.argc], res)
+		case opJump:
+			pc = in.target
+		case opJumpIfZero, opJumpIfNotZero:
+			cond := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if (cond == 0) == (in.op == opJumpIfZero) {
+				pc = in.target
+			}
+		}
+	}
+
+	return stack[0], nil
+}
@@ -0,0 +1,142 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "no input", input: "", wantErr: true},
+		{name: "simple calculation", input: "5+4*x"},
+		{name: "with function", input: "COS(x)"},
+		{name: "unknown function", input: "LOOL(5)", wantErr: true},
+		{name: "number too large for float64", input: "2 * 1e400", wantErr: true},
+		{name: "invalid calculation", input: "((2*(5+3))+4)+", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Compile(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got == nil {
+				t.Error("Compile() got nil program")
+			}
+		})
+	}
+}
+
+func TestProgram_Eval(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		vars    map[string]float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "without variables", input: "((2*(5+3))+4)*(300/100)", want: 60},
+		{name: "with constant", input: "2^3+PI", want: 11.141592653589793},
+		{name: "with function", input: "COS(3+2)", want: 0.2836621854632263},
+		{name: "with variables", input: "x^2+3*y", vars: map[string]float64{"X": 4, "Y": 2}, want: 22},
+		{name: "variable shadows constant", input: "PI*2", vars: map[string]float64{"PI": 3}, want: 6},
+		{name: "variable inside function", input: "SQRT(x*x)", vars: map[string]float64{"X": 7}, want: 7},
+		{name: "missing variable", input: "x+1", wantErr: true},
+		{name: "comparison", input: "x >= 2", vars: map[string]float64{"X": 2}, want: 1},
+		{name: "logical not", input: "!x", vars: map[string]float64{"X": 0}, want: 1},
+		{name: "and short-circuits", input: "x != 0 && 1/x > 0.5", vars: map[string]float64{"X": 0}, want: 0},
+		{name: "and evaluates both", input: "x != 0 && 1/x > 0.5", vars: map[string]float64{"X": 1}, want: 1},
+		{name: "or short-circuits", input: "x == 0 || 1/x", vars: map[string]float64{"X": 0}, want: 1},
+		{name: "or normalizes to 1", input: "x == 0 || 1/x", vars: map[string]float64{"X": 4}, want: 1},
+		{name: "conditional then", input: "x > 0 ? SQRT(x) : -1", vars: map[string]float64{"X": 16}, want: 4},
+		{name: "conditional else", input: "x > 0 ? SQRT(x) : -1", vars: map[string]float64{"X": -4}, want: -1},
+		{name: "nested conditional", input: "x < 0 ? -1 : x == 0 ? 0 : 1", vars: map[string]float64{"X": 0}, want: 0},
+		{name: "bitwise operators", input: "~x & 0xFF | 1 << 8", vars: map[string]float64{"X": 0x0F}, want: 0x1F0},
+		{name: "bitwise not with fraction", input: "~x", vars: map[string]float64{"X": 0.5}, wantErr: true},
+		{name: "conditional inside expression", input: "2 * (x ? 3 : 4) + 1", vars: map[string]float64{"X": 0}, want: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := calc.Compile(tt.input)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+
+			got, err := p.Eval(tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Eval() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileOptions(t *testing.T) {
+	r := calc.NewRegistry()
+	if err := r.Register("DOUBLE", 1, 1, func(args ...float64) (float64, error) {
+		return 2 * args[0], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := calc.CompileOptions("DOUBLE(x) + 1", calc.ProgramOptions{Registry: r})
+	if err != nil {
+		t.Fatalf("CompileOptions() error = %v", err)
+	}
+	if got, err := p.Eval(calc.Env{"X": 3}); err != nil || got != 7 {
+		t.Errorf("Eval() = %v, %v, want 7", got, err)
+	}
+
+	if _, err := calc.CompileOptions("SQRT(4)", calc.ProgramOptions{Registry: r}); err == nil {
+		t.Error("CompileOptions() found a function of the DefaultRegistry")
+	}
+}
+
+const benchmarkExpr = "((2*(5+3))+4)*(300/100)+COS(PI/3)^2-SQRT(16)"
+
+func BenchmarkSolve(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := calc.Solve(benchmarkExpr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgram_Eval(b *testing.B) {
+	p, err := calc.Compile(benchmarkExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Eval(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgram_EvalWithVariables(b *testing.B) {
+	p, err := calc.Compile("x^2+3*x-COS(y)")
+	if err != nil {
+		b.Fatal(err)
+	}
+	vars := map[string]float64{"X": 0, "Y": 1}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vars["X"] = float64(i)
+		if _, err := p.Eval(vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
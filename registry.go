@@ -0,0 +1,292 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Variadic can be used as maximum argument count of a function to accept
+// any amount of arguments.
+const Variadic = -1
+
+// Func is a function which can be called from an expression.
+type Func func(args ...float64) (float64, error)
+
+// arity is the range of argument counts a function accepts.
+type arity struct {
+	min, max int
+}
+
+// checkArity returns an error if the function cannot be called with n arguments.
+func (a arity) checkArity(name string, n int) error {
+	switch {
+	case a.min == a.max && n != a.min:
+		return &EvalError{Kind: ArgumentCount, Msg: fmt.Sprintf("function %s expects %d argument(s), got %d", name, a.min, n)}
+	case n < a.min:
+		return &EvalError{Kind: ArgumentCount, Msg: fmt.Sprintf("function %s expects at least %d argument(s), got %d", name, a.min, n)}
+	case a.max != Variadic && n > a.max:
+		return &EvalError{Kind: ArgumentCount, Msg: fmt.Sprintf("function %s expects at most %d argument(s), got %d", name, a.max, n)}
+	}
+	return nil
+}
+
+type funcEntry struct {
+	arity
+	fn Func
+}
+
+// Registry holds the functions which can be called from expressions.
+// It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]funcEntry
+}
+
+// DefaultRegistry contains all built-in functions.
+// It is used by Solve, Eval and Compile and more functions can be registered to it.
+// SolveOptions.Registry and ProgramOptions.Registry select another Registry.
+var DefaultRegistry = newDefaultRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{funcs: map[string]funcEntry{}}
+}
+
+// Register adds a function which accepts between minArgs and maxArgs arguments.
+// maxArgs may be Variadic to accept any amount of arguments.
+// The Scanner upper-cases all identifiers, so the name is upper-cased too.
+// An already registered function with the same name is replaced.
+func (r *Registry) Register(name string, minArgs, maxArgs int, fn Func) error {
+	if name == "" {
+		return errors.New("function name must not be empty")
+	}
+	if fn == nil {
+		return fmt.Errorf("function %s must not be nil", name)
+	}
+	if minArgs < 0 || (maxArgs != Variadic && maxArgs < minArgs) {
+		return fmt.Errorf("invalid argument count %d to %d for function %s", minArgs, maxArgs, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[strings.ToUpper(name)] = funcEntry{arity: arity{min: minArgs, max: maxArgs}, fn: fn}
+	return nil
+}
+
+// orDefault returns r or the DefaultRegistry if r is nil.
+func (r *Registry) orDefault() *Registry {
+	if r == nil {
+		return DefaultRegistry
+	}
+	return r
+}
+
+// lookup returns the function with the given name.
+func (r *Registry) lookup(name string) (funcEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.funcs[name]
+	if !ok {
+		return funcEntry{}, &EvalError{Kind: UnknownFunction, Msg: fmt.Sprintf("function does not exist: %s%s", name, suggestion(name, r.names()))}
+	}
+	return f, nil
+}
+
+// has reports whether the function with the given name is registered.
+func (r *Registry) has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.funcs[name]
+	return ok
+}
+
+// Names returns the sorted names of all registered functions.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names()
+}
+
+// names returns the sorted function names, r.mu has to be locked by the caller.
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call calls the function with the given name after checking the argument count.
+func (r *Registry) Call(name string, args ...float64) (float64, error) {
+	f, err := r.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.checkArity(name, len(args)); err != nil {
+		return 0, err
+	}
+
+	return f.call(name, args)
+}
+
+// call calls the function and checks its result.
+func (f funcEntry) call(name string, args []float64) (float64, error) {
+	res, err := f.fn(args...)
+	if err != nil {
+		return 0, &EvalError{Kind: FunctionError, Msg: name, Err: err}
+	}
+	if math.IsNaN(res) && !containsNaN(args) {
+		return 0, &EvalError{Kind: DomainError, Msg: fmt.Sprintf("%s is not defined for %v", name, args)}
+	}
+	return res, nil
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for name, function := range funcs {
+		function := function
+		r.mustRegister(name, 1, 1, func(args ...float64) (float64, error) {
+			return function(args[0]), nil
+		})
+	}
+
+	r.mustRegister("MIN", 1, Variadic, func(args ...float64) (float64, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			res = math.Min(res, v)
+		}
+		return res, nil
+	})
+	r.mustRegister("MAX", 1, Variadic, func(args ...float64) (float64, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			res = math.Max(res, v)
+		}
+		return res, nil
+	})
+	r.mustRegister("SUM", 1, Variadic, func(args ...float64) (float64, error) {
+		return sum(args), nil
+	})
+	r.mustRegister("AVG", 1, Variadic, func(args ...float64) (float64, error) {
+		return sum(args) / float64(len(args)), nil
+	})
+	r.mustRegister("POW", 2, 2, func(args ...float64) (float64, error) {
+		return math.Pow(args[0], args[1]), nil
+	})
+	r.mustRegister("LOG", 1, 2, func(args ...float64) (float64, error) {
+		if len(args) == 1 {
+			return math.Log10(args[0]), nil
+		}
+		return math.Log(args[0]) / math.Log(args[1]), nil
+	})
+	r.mustRegister("ATAN2", 2, 2, func(args ...float64) (float64, error) {
+		return math.Atan2(args[0], args[1]), nil
+	})
+	r.mustRegister("HYPOT", 2, 2, func(args ...float64) (float64, error) {
+		return math.Hypot(args[0], args[1]), nil
+	})
+	r.mustRegister("ROUND", 1, 2, func(args ...float64) (float64, error) {
+		if len(args) == 1 {
+			return math.Round(args[0]), nil
+		}
+		if args[1] != math.Trunc(args[1]) {
+			return 0, fmt.Errorf("digits must be an integer, got %v", args[1])
+		}
+		// The result does not change beyond these digits, see roundDigits.
+		digits := math.Max(-maxRoundDigits, math.Min(maxRoundDigits, args[1]))
+		return roundDigits(args[0], int(digits)), nil
+	})
+	r.mustRegister("FACT", 1, 1, func(args ...float64) (float64, error) {
+		return factorial(args[0]), nil
+	})
+	r.mustRegister("CLAMP", 3, 3, func(args ...float64) (float64, error) {
+		if args[1] > args[2] {
+			return 0, fmt.Errorf("lower bound %v is greater than upper bound %v", args[1], args[2])
+		}
+		return math.Min(math.Max(args[0], args[1]), args[2]), nil
+	})
+
+	return r
+}
+
+func (r *Registry) mustRegister(name string, minArgs, maxArgs int, fn Func) {
+	if err := r.Register(name, minArgs, maxArgs, fn); err != nil {
+		panic(err)
+	}
+}
+
+// maxRoundDigits is larger than the amount of digits which roundDigits
+// needs for any float64.
+const maxRoundDigits = 400
+
+// roundDigits rounds x to the given amount of fraction digits, halves away
+// from zero. Negative digits round to tens, hundreds and so on.
+func roundDigits(x float64, digits int) float64 {
+	if x == 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+		return x
+	}
+
+	// intDigits is the amount of digits before the decimal point,
+	// which is negative for numbers below 0.1.
+	intDigits := int(math.Floor(math.Log10(math.Abs(x)))) + 1
+	switch {
+	case intDigits+digits > 17:
+		// A float64 does not have more significant digits.
+		return x
+	case intDigits+digits < 0:
+		// |x| is below a tenth of the rounding scale.
+		return math.Copysign(0, x)
+	case digits > 300:
+		// The scale of tiny numbers is beyond the range of float64.
+		return roundDigits(x*1e300, digits-300) / 1e300
+	case digits < 0:
+		scale := math.Pow10(-digits)
+		if math.IsInf(scale, 0) {
+			return math.Copysign(0, x)
+		}
+		return math.Round(x/scale) * scale
+	}
+
+	scale := math.Pow10(digits)
+	return math.Round(x*scale) / scale
+}
+
+// factorial returns x! for non-negative integers and Gamma(x+1) for other numbers.
+// It is NaN for negative integers.
+func factorial(x float64) float64 {
+	if x != math.Trunc(x) || x > 170 {
+		return math.Gamma(x + 1)
+	}
+	if x < 0 {
+		return math.NaN()
+	}
+
+	res := 1.0
+	for i := 2.0; i <= x; i++ {
+		res *= i
+	}
+	return res
+}
+
+func containsNaN(values []float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func sum(values []float64) float64 {
+	var res float64
+	for _, v := range values {
+		res += v
+	}
+	return res
+}
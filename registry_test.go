@@ -0,0 +1,162 @@
+package calc_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	fn := func(args ...float64) (float64, error) { return 0, nil }
+
+	tests := []struct {
+		name    string
+		fnName  string
+		min     int
+		max     int
+		fn      calc.Func
+		wantErr bool
+	}{
+		{name: "fixed arity", fnName: "F", min: 2, max: 2, fn: fn},
+		{name: "variadic", fnName: "F", min: 0, max: calc.Variadic, fn: fn},
+		{name: "empty name", fnName: "", min: 1, max: 1, fn: fn, wantErr: true},
+		{name: "nil function", fnName: "F", min: 1, max: 1, fn: nil, wantErr: true},
+		{name: "negative min", fnName: "F", min: -1, max: 1, fn: fn, wantErr: true},
+		{name: "max lower than min", fnName: "F", min: 2, max: 1, fn: fn, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := calc.NewRegistry().Register(tt.fnName, tt.min, tt.max, tt.fn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Call(t *testing.T) {
+	r := calc.NewRegistry()
+	sum := func(args ...float64) (float64, error) {
+		var res float64
+		for _, v := range args {
+			res += v
+		}
+		return res, nil
+	}
+	if err := r.Register("two", 2, 2, sum); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("SOME", 1, 3, sum); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("ANY", 0, calc.Variadic, sum); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("FAIL", 0, 0, func(args ...float64) (float64, error) {
+		return 0, errors.New("some error")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fnName  string
+		args    []float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "fixed arity", fnName: "TWO", args: []float64{1, 2}, want: 3},
+		{name: "too few arguments", fnName: "TWO", args: []float64{1}, wantErr: true},
+		{name: "too many arguments", fnName: "TWO", args: []float64{1, 2, 3}, wantErr: true},
+		{name: "range arity", fnName: "SOME", args: []float64{1, 2}, want: 3},
+		{name: "range arity too many", fnName: "SOME", args: []float64{1, 2, 3, 4}, wantErr: true},
+		{name: "variadic without arguments", fnName: "ANY", want: 0},
+		{name: "variadic with many arguments", fnName: "ANY", args: []float64{1, 2, 3, 4, 5}, want: 15},
+		{name: "failing function", fnName: "FAIL", wantErr: true},
+		{name: "unknown function", fnName: "LOOL", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Call(tt.fnName, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Call() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Names(t *testing.T) {
+	r := calc.NewRegistry()
+	for _, name := range []string{"two", "ANY", "SOME"} {
+		if err := r.Register(name, 0, calc.Variadic, func(args ...float64) (float64, error) { return 0, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := r.Names(), []string{"ANY", "SOME", "TWO"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() got = %v, want %v", got, want)
+	}
+
+	_, err := r.Call("SOM", 1)
+	if want := "function does not exist: SOM, did you mean SOME?"; err == nil || err.Error() != want {
+		t.Errorf("Call() error = %v, want %v", err, want)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{name: "single argument builtin", input: "SQRT(16)", want: 4},
+		{name: "min", input: "MIN(3, 1, 2)", want: 1},
+		{name: "max", input: "MAX(3, 1, 2)", want: 3},
+		{name: "max without arguments", input: "MAX()", wantErr: true},
+		{name: "pow", input: "POW(2, 10)", want: 1024},
+		{name: "log with base 10", input: "LOG(1000)", want: 3},
+		{name: "log with base", input: "LOG(8, 2)", want: 3},
+		{name: "atan2", input: "ATAN2(1, 1)", want: math.Pi / 4},
+		{name: "hypot", input: "HYPOT(3, 4)", want: 5},
+		{name: "round", input: "ROUND(2.5)", want: 3},
+		{name: "round with digits", input: "ROUND(3.14159, 2)", want: 3.14},
+		{name: "round with negative digits", input: "ROUND(1234, -2)", want: 1200},
+		{name: "round with more digits than float64", input: "ROUND(1.5, 400)", want: 1.5},
+		{name: "round with huge digits", input: "ROUND(1.5, 10^20)", want: 1.5},
+		{name: "round to zero", input: "ROUND(1234.5, -400)", want: 0},
+		{name: "round to the largest scale", input: "ROUND(1.7e308, -309)", want: 0},
+		{name: "round large number", input: "ROUND(1.2e308, -308)", want: 1e308},
+		{name: "round tiny number", input: "ROUND(1.6e-310, 310) * 1e300 * 1e10", want: 2},
+		{name: "round with invalid digits", input: "ROUND(3.14159, 2.5)", wantErr: true},
+		{name: "clamp below", input: "CLAMP(-5, 0, 10)", want: 0},
+		{name: "clamp above", input: "CLAMP(15, 0, 10)", want: 10},
+		{name: "clamp inside", input: "CLAMP(5, 0, 10)", want: 5},
+		{name: "clamp with invalid bounds", input: "CLAMP(5, 10, 0)", wantErr: true},
+		{name: "sum", input: "SUM(1, 2, 3, 4)", want: 10},
+		{name: "avg", input: "AVG(1, 2, 3, 4)", want: 2.5},
+		{name: "nested calls", input: "MAX(1, MIN(5, 4), 2) + 1", want: 5},
+		{name: "calculations as arguments", input: "POW(1+1, 2*3)", want: 64},
+		{name: "wrong argument count", input: "HYPOT(3)", wantErr: true},
+		{name: "empty argument", input: "MAX(1, )", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Solve(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Solve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Solve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,23 +8,82 @@ import (
 	"io"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// ScanOptions configures a Scanner.
+type ScanOptions struct {
+	// SpecialFloats makes INF and NAN number literals instead of identifiers.
+	SpecialFloats bool
+
+	// CaseSensitive keeps the case of identifiers, so that dt and dT are
+	// different variables. Otherwise all identifiers are upper-cased.
+	// Built-in constants, functions and word operators such as XOR are
+	// matched case-insensitively in both cases, but a variable with the exact
+	// name of a constant shadows it.
+	CaseSensitive bool
+
+	// IsIdentRune reports whether ch may be the i-th rune of an identifier,
+	// counted from 0. If it is nil, DefaultIdentRune is used.
+	// A digit at the start is always scanned as a number.
+	IsIdentRune func(ch rune, i int) bool
+}
+
+// DefaultIdentRune is the identifier rule used if ScanOptions.IsIdentRune is nil.
+// Identifiers start with a letter or "_" and may contain digits and dots
+// after that, such as room_1.width.
+func DefaultIdentRune(ch rune, i int) bool {
+	return unicode.IsLetter(ch) || ch == '_' || (i > 0 && (unicode.IsDigit(ch) || ch == '.'))
+}
+
 type Scanner struct {
-	r *bufio.Reader
+	r    *bufio.Reader
+	opts ScanOptions
+
+	// pos is the position of the next rune.
+	pos Position
+	// prev is the position before the last Read, used by Unread.
+	prev Position
 }
 
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r)}
+	return NewScannerOptions(r, ScanOptions{})
+}
+
+// NewScannerOptions returns a Scanner which is configured by opts.
+func NewScannerOptions(r io.Reader, opts ScanOptions) *Scanner {
+	return &Scanner{r: bufio.NewReader(r), opts: opts, pos: Position{Line: 1, Column: 1}}
 }
 
 func (s *Scanner) Read() (rune, error) {
-	ch, _, err := s.r.ReadRune()
-	return ch, err
+	ch, size, err := s.r.ReadRune()
+	if err != nil {
+		return ch, err
+	}
+
+	s.prev = s.pos
+	s.pos.Offset += size
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
+	return ch, nil
 }
 
 func (s *Scanner) Unread() error {
-	return s.r.UnreadRune()
+	if err := s.r.UnreadRune(); err != nil {
+		return err
+	}
+
+	s.pos = s.prev
+	return nil
+}
+
+// token returns a token which starts at start and ends at the current position.
+func (s *Scanner) token(typ TokenType, value string, start Position) Token {
+	return Token{Type: typ, Value: value, Pos: start, End: s.pos}
 }
 
 func (s *Scanner) loadNextRuneTo(buf *bytes.Buffer) error {
@@ -38,6 +97,11 @@ func (s *Scanner) loadNextRuneTo(buf *bytes.Buffer) error {
 }
 
 func (s *Scanner) Scan() (Token, error) {
+	// A number may start with its decimal point, such as .5.
+	if next, _ := s.r.Peek(2); len(next) == 2 && next[0] == '.' && isASCIIDigit(rune(next[1])) {
+		return s.ScanNumber()
+	}
+
 	ch, err := s.Read()
 	if err != nil {
 		return Token{}, err
@@ -50,7 +114,7 @@ func (s *Scanner) Scan() (Token, error) {
 		}
 
 		return s.ScanNumber()
-	} else if unicode.IsLetter(ch) {
+	} else if s.isIdentRune(ch, 0) {
 		err = s.Unread()
 		if err != nil {
 			return Token{}, err
@@ -58,7 +122,7 @@ func (s *Scanner) Scan() (Token, error) {
 
 		return s.ScanWord()
 	} else if IsOperator(ch) {
-		return Token{Operator, string(ch)}, nil
+		return s.ScanOperator(ch)
 	} else if unicode.IsSpace(ch) {
 		err = s.Unread()
 		if err != nil {
@@ -70,58 +134,31 @@ func (s *Scanner) Scan() (Token, error) {
 
 	switch ch {
 	case '(':
-		return Token{Lparen, "("}, nil
+		return s.token(Lparen, "(", s.prev), nil
 	case ')':
-		return Token{Rparen, ")"}, nil
+		return s.token(Rparen, ")", s.prev), nil
+	case ',':
+		return s.token(Comma, ",", s.prev), nil
+	case ';':
+		return s.token(Semicolon, ";", s.prev), nil
 	}
 
-	return Token{}, fmt.Errorf("invalid token %v", ch)
+	return Token{}, &SyntaxError{Kind: InvalidToken, Pos: s.prev, Msg: fmt.Sprintf("invalid token %q", ch)}
 }
 
 func (s *Scanner) ScanWord() (Token, error) {
+	start := s.pos
 	var buf bytes.Buffer
 	if err := s.loadNextRuneTo(&buf); err != nil {
 		return Token{}, err
 	}
 
-	for {
+	for i := 1; ; i++ {
 		if ch, err := s.Read(); errors.Is(err, io.EOF) {
 			break
 		} else if err != nil {
 			return Token{}, err
-		} else if ch == '(' {
-			_, err = buf.WriteRune(ch)
-			if err != nil {
-				return Token{}, err
-			}
-
-			parentCount := 1
-			for parentCount > 0 {
-				fch, err := s.Read()
-				if err != nil {
-					return Token{}, err
-				}
-
-				if fch == '(' {
-					parentCount += 1
-					_, err = buf.WriteRune(fch)
-					if err != nil {
-						return Token{}, err
-					}
-				} else if fch == ')' {
-					parentCount -= 1
-					_, err = buf.WriteRune(fch)
-					if err != nil {
-						return Token{}, err
-					}
-				} else {
-					_, err = buf.WriteRune(fch)
-					if err != nil {
-						return Token{}, err
-					}
-				}
-			}
-		} else if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+		} else if !s.isIdentRune(ch, i) {
 			err = s.Unread()
 			if err != nil {
 				return Token{}, err
@@ -135,43 +172,246 @@ func (s *Scanner) ScanWord() (Token, error) {
 		}
 	}
 
-	value := strings.ToUpper(buf.String())
-	if strings.ContainsAny(value, "()") {
-		return Token{Function, value}, nil
-	} else {
-		return Token{Constant, value}, nil
+	value := buf.String()
+	upper := strings.ToUpper(value)
+	if !s.opts.CaseSensitive {
+		value = upper
+	}
+	if wordOperators[upper] {
+		return s.token(Operator, upper, start), nil
 	}
+	if s.opts.SpecialFloats && (upper == "INF" || upper == "NAN") {
+		return s.token(Number, upper, start), nil
+	}
+
+	// A word directly followed by a parenthesis is a function call.
+	// The Parser decides about a word with spaces before the parenthesis.
+	// Functions are always upper-case, like in the Registry.
+	if ch, err := s.Read(); errors.Is(err, io.EOF) {
+		return s.token(Constant, value, start), nil
+	} else if err != nil {
+		return Token{}, err
+	} else if err := s.Unread(); err != nil {
+		return Token{}, err
+	} else if ch == '(' {
+		return s.token(Function, upper, start), nil
+	}
+
+	return s.token(Constant, value, start), nil
+}
+
+// isIdentRune reports whether ch may be the i-th rune of an identifier.
+func (s *Scanner) isIdentRune(ch rune, i int) bool {
+	if s.opts.IsIdentRune == nil {
+		return DefaultIdentRune(ch, i)
+	}
+	return s.opts.IsIdentRune(ch, i)
 }
 
 func (s *Scanner) ScanNumber() (Token, error) {
+	start := s.pos
 	var buf bytes.Buffer
 	if err := s.loadNextRuneTo(&buf); err != nil {
 		return Token{}, err
 	}
 
-	for {
-		if ch, err := s.Read(); errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
+	if buf.String() == "0" && strings.ContainsRune("xXbBoO", s.peek()) {
+		if err := s.loadNextRuneTo(&buf); err != nil {
 			return Token{}, err
-		} else if !unicode.IsDigit(ch) && ch != '.' {
-			err = s.Unread()
-			if err != nil {
-				return Token{}, err
+		}
+		if err := s.scanBaseDigits(&buf, start); err != nil {
+			return Token{}, err
+		}
+	} else if err := s.scanDecimal(&buf); err != nil {
+		return Token{}, err
+	}
+
+	// An i directly after a number, which does not start a word, makes it imaginary.
+	if s.imaginarySuffix() {
+		if err := s.loadNextRuneTo(&buf); err != nil {
+			return Token{}, err
+		}
+	}
+
+	if ch := s.peek(); isASCIIDigit(ch) || ch == '.' || ch == '_' {
+		return Token{}, s.numberError(s.pos, "invalid number %q: unexpected %q", buf.String(), ch)
+	}
+
+	return s.token(Number, buf.String(), start), nil
+}
+
+// scanDecimal scans the rest of a decimal number whose first rune is in buf.
+// It consists of digits, an optional fraction and an optional exponent such as e-3.
+func (s *Scanner) scanDecimal(buf *bytes.Buffer) error {
+	if first, _ := utf8.DecodeRune(buf.Bytes()); !isASCIIDigit(first) && first != '.' {
+		return s.numberError(s.prev, "invalid number %q: only the digits 0-9 are allowed", buf.String())
+	}
+	if buf.String() != "." {
+		if err := s.scanDigits(buf); err != nil {
+			return err
+		}
+		if s.peek() == '.' {
+			if err := s.loadNextRuneTo(buf); err != nil {
+				return err
 			}
-			break
-		} else {
-			_, err = buf.WriteRune(ch)
-			if err != nil {
-				return Token{}, err
+		}
+	}
+	if strings.HasSuffix(buf.String(), ".") && isASCIIDigit(s.peek()) {
+		if err := s.scanDigits(buf); err != nil {
+			return err
+		}
+	}
+
+	// An e which is not followed by a digit or sign starts a word instead of an exponent.
+	next, _ := s.r.Peek(3)
+	if len(next) < 2 || (next[0] != 'e' && next[0] != 'E') {
+		return nil
+	}
+	if next[1] == '+' || next[1] == '-' {
+		if len(next) < 3 || !isASCIIDigit(rune(next[2])) {
+			// Point at the rune after the sign.
+			pos := s.pos
+			pos.Offset += 2
+			pos.Column += 2
+			return s.numberError(pos, "invalid number %q: exponent has no digits", buf.String()+string(next[:2]))
+		}
+		if err := s.loadNextRuneTo(buf); err != nil {
+			return err
+		}
+	} else if !isASCIIDigit(rune(next[1])) {
+		return nil
+	}
+	if err := s.loadNextRuneTo(buf); err != nil {
+		return err
+	}
+	return s.scanDigits(buf)
+}
+
+// scanDigits scans decimal digits, which may be separated by single underscores.
+func (s *Scanner) scanDigits(buf *bytes.Buffer) error {
+	for {
+		ch := s.peek()
+		if ch == '_' {
+			pos := s.pos
+			if err := s.loadNextRuneTo(buf); err != nil {
+				return err
+			}
+			if !isASCIIDigit(rune(buf.Bytes()[buf.Len()-2])) || !isASCIIDigit(s.peek()) {
+				return s.numberError(pos, "invalid number %q: '_' must separate digits", buf.String())
+			}
+			continue
+		}
+		if !isASCIIDigit(ch) {
+			return nil
+		}
+		if err := s.loadNextRuneTo(buf); err != nil {
+			return err
+		}
+	}
+}
+
+// scanBaseDigits scans the digits after a base prefix such as 0x, which is already in buf.
+func (s *Scanner) scanBaseDigits(buf *bytes.Buffer, start Position) error {
+	base, name := 16, "hexadecimal"
+	switch buf.String()[1] {
+	case 'b', 'B':
+		base, name = 2, "binary"
+	case 'o', 'O':
+		base, name = 8, "octal"
+	}
+
+	for {
+		ch := s.peek()
+		pos := s.pos
+		if ch == '_' {
+			if err := s.loadNextRuneTo(buf); err != nil {
+				return err
 			}
+			// Go also allows an underscore directly after the prefix.
+			if ch := s.peek(); !unicode.Is(unicode.ASCII_Hex_Digit, ch) || digitValue(ch) >= base {
+				return s.numberError(pos, "invalid number %q: '_' must separate digits", buf.String())
+			}
+			continue
+		}
+		if !unicode.Is(unicode.ASCII_Hex_Digit, ch) {
+			break
+		}
+		if digitValue(ch) >= base {
+			return s.numberError(pos, "invalid digit %q in %s number", ch, name)
+		}
+		if err := s.loadNextRuneTo(buf); err != nil {
+			return err
+		}
+	}
+
+	if buf.Len() == 2 {
+		return s.numberError(start, "invalid number %q: %s number has no digits", buf.String(), name)
+	}
+	return nil
+}
+
+// digitValue returns the value of a hexadecimal digit.
+func digitValue(ch rune) int {
+	switch {
+	case ch >= 'a':
+		return int(ch-'a') + 10
+	case ch >= 'A':
+		return int(ch-'A') + 10
+	}
+	return int(ch - '0')
+}
+
+// peek returns the next rune without reading it, or 0 at the end of the input.
+func (s *Scanner) peek() rune {
+	next, _ := s.r.Peek(utf8.UTFMax)
+	if len(next) == 0 {
+		return 0
+	}
+	ch, _ := utf8.DecodeRune(next)
+	return ch
+}
+
+func (s *Scanner) numberError(pos Position, format string, args ...interface{}) error {
+	return &SyntaxError{Kind: InvalidNumber, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// imaginarySuffix reports whether the next rune is an i or I which does not start an identifier.
+func (s *Scanner) imaginarySuffix() bool {
+	next, _ := s.r.Peek(1 + utf8.UTFMax)
+	if len(next) == 0 || (next[0] != 'i' && next[0] != 'I') {
+		return false
+	}
+	if len(next) == 1 {
+		return true
+	}
+
+	ch, _ := utf8.DecodeRune(next[1:])
+	return !s.isIdentRune(ch, 1)
+}
+
+// ScanOperator scans the longest operator which starts with the already read rune first.
+func (s *Scanner) ScanOperator(first rune) (Token, error) {
+	start := s.prev
+
+	if ch, err := s.Read(); err == nil {
+		if value := string(first) + string(ch); operators[value] {
+			return s.token(Operator, value, start), nil
 		}
+		if err := s.Unread(); err != nil {
+			return Token{}, err
+		}
+	} else if !errors.Is(err, io.EOF) {
+		return Token{}, err
 	}
 
-	return Token{Number, buf.String()}, nil
+	if !operators[string(first)] {
+		return Token{}, &SyntaxError{Kind: InvalidToken, Pos: start, Msg: fmt.Sprintf("invalid token %q", first)}
+	}
+	return s.token(Operator, string(first), start), nil
 }
 
 func (s *Scanner) ScanWhitespace() (Token, error) {
+	start := s.pos
 	var buf bytes.Buffer
 	if err := s.loadNextRuneTo(&buf); err != nil {
 		return Token{}, err
@@ -193,9 +433,24 @@ func (s *Scanner) ScanWhitespace() (Token, error) {
 		}
 	}
 
-	return Token{Whitespace, buf.String()}, nil
+	return s.token(Whitespace, buf.String(), start), nil
+}
+
+// operators are all operators the scanner recognizes.
+var operators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "^": true, "%": true, "//": true,
+	"<": true, "<=": true, ">": true, ">=": true, "==": true, "!=": true,
+	"&&": true, "||": true, "!": true, "?": true, ":": true,
+	"&": true, "|": true, "~": true, "<<": true, ">>": true,
+	"=": true,
+}
+
+// wordOperators are operators which are written like identifiers.
+var wordOperators = map[string]bool{
+	"XOR": true,
 }
 
+// IsOperator reports whether r is the first rune of an operator.
 func IsOperator(r rune) bool {
-	return r == '+' || r == '-' || r == '*' || r == '/' || r == '^'
+	return strings.ContainsRune("+-*/^%<>=!&|?:~", r)
 }
@@ -0,0 +1,289 @@
+package calc_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/aligator/calc"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	at := func(offset, line, column int) calc.Position {
+		return calc.Position{Offset: offset, Line: line, Column: column}
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		opts  calc.ScanOptions
+		want  []calc.Token
+	}{
+		{name: "empty input", input: "", want: nil},
+		{
+			name:  "numbers and operators",
+			input: "12.5+3",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "12.5", Pos: at(0, 1, 1), End: at(4, 1, 5)},
+				{Type: calc.Operator, Value: "+", Pos: at(4, 1, 5), End: at(5, 1, 6)},
+				{Type: calc.Number, Value: "3", Pos: at(5, 1, 6), End: at(6, 1, 7)},
+			},
+		},
+		{
+			name:  "function call and constant",
+			input: "cos(pi)",
+			want: []calc.Token{
+				{Type: calc.Function, Value: "COS", Pos: at(0, 1, 1), End: at(3, 1, 4)},
+				{Type: calc.Lparen, Value: "(", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Constant, Value: "PI", Pos: at(4, 1, 5), End: at(6, 1, 7)},
+				{Type: calc.Rparen, Value: ")", Pos: at(6, 1, 7), End: at(7, 1, 8)},
+			},
+		},
+		{
+			name:  "spaces before parenthesis",
+			input: "cos \t(0)",
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "COS", Pos: at(0, 1, 1), End: at(3, 1, 4)},
+				{Type: calc.Whitespace, Value: " \t", Pos: at(3, 1, 4), End: at(5, 1, 6)},
+				{Type: calc.Lparen, Value: "(", Pos: at(5, 1, 6), End: at(6, 1, 7)},
+				{Type: calc.Number, Value: "0", Pos: at(6, 1, 7), End: at(7, 1, 8)},
+				{Type: calc.Rparen, Value: ")", Pos: at(7, 1, 8), End: at(8, 1, 9)},
+			},
+		},
+		{
+			name:  "line break ends word",
+			input: "x\n(1)",
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "X", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Whitespace, Value: "\n", Pos: at(1, 1, 2), End: at(2, 2, 1)},
+				{Type: calc.Lparen, Value: "(", Pos: at(2, 2, 1), End: at(3, 2, 2)},
+				{Type: calc.Number, Value: "1", Pos: at(3, 2, 2), End: at(4, 2, 3)},
+				{Type: calc.Rparen, Value: ")", Pos: at(4, 2, 3), End: at(5, 2, 4)},
+			},
+		},
+		{
+			name:  "several lines",
+			input: "1,\n  x",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "1", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Comma, Value: ",", Pos: at(1, 1, 2), End: at(2, 1, 3)},
+				{Type: calc.Whitespace, Value: "\n  ", Pos: at(2, 1, 3), End: at(5, 2, 3)},
+				{Type: calc.Constant, Value: "X", Pos: at(5, 2, 3), End: at(6, 2, 4)},
+			},
+		},
+		{
+			name:  "multi byte runes",
+			input: " π",
+			want: []calc.Token{
+				{Type: calc.Whitespace, Value: " ", Pos: at(0, 1, 1), End: at(2, 1, 2)},
+				{Type: calc.Constant, Value: "Π", Pos: at(2, 1, 2), End: at(4, 1, 3)},
+			},
+		},
+		{
+			name:  "multi character operators",
+			input: "1<=2&&!x",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "1", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Operator, Value: "<=", Pos: at(1, 1, 2), End: at(3, 1, 4)},
+				{Type: calc.Number, Value: "2", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Operator, Value: "&&", Pos: at(4, 1, 5), End: at(6, 1, 7)},
+				{Type: calc.Operator, Value: "!", Pos: at(6, 1, 7), End: at(7, 1, 8)},
+				{Type: calc.Constant, Value: "X", Pos: at(7, 1, 8), End: at(8, 1, 9)},
+			},
+		},
+		{
+			name:  "number literals with base",
+			input: "0x1F&0b1_0",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "0x1F", Pos: at(0, 1, 1), End: at(4, 1, 5)},
+				{Type: calc.Operator, Value: "&", Pos: at(4, 1, 5), End: at(5, 1, 6)},
+				{Type: calc.Number, Value: "0b1_0", Pos: at(5, 1, 6), End: at(10, 1, 11)},
+			},
+		},
+		{
+			name:  "word operator",
+			input: "1 xor x",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "1", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Whitespace, Value: " ", Pos: at(1, 1, 2), End: at(2, 1, 3)},
+				{Type: calc.Operator, Value: "XOR", Pos: at(2, 1, 3), End: at(5, 1, 6)},
+				{Type: calc.Whitespace, Value: " ", Pos: at(5, 1, 6), End: at(6, 1, 7)},
+				{Type: calc.Constant, Value: "X", Pos: at(6, 1, 7), End: at(7, 1, 8)},
+			},
+		},
+		{
+			name:  "imaginary numbers",
+			input: "2i*1.5I",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "2i", Pos: at(0, 1, 1), End: at(2, 1, 3)},
+				{Type: calc.Operator, Value: "*", Pos: at(2, 1, 3), End: at(3, 1, 4)},
+				{Type: calc.Number, Value: "1.5I", Pos: at(3, 1, 4), End: at(7, 1, 8)},
+			},
+		},
+		{
+			name:  "exponents",
+			input: "1e10-6.02E+23*1.5e-3",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "1e10", Pos: at(0, 1, 1), End: at(4, 1, 5)},
+				{Type: calc.Operator, Value: "-", Pos: at(4, 1, 5), End: at(5, 1, 6)},
+				{Type: calc.Number, Value: "6.02E+23", Pos: at(5, 1, 6), End: at(13, 1, 14)},
+				{Type: calc.Operator, Value: "*", Pos: at(13, 1, 14), End: at(14, 1, 15)},
+				{Type: calc.Number, Value: "1.5e-3", Pos: at(14, 1, 15), End: at(20, 1, 21)},
+			},
+		},
+		{
+			name:  "leading and trailing dot",
+			input: ".5+5.",
+			want: []calc.Token{
+				{Type: calc.Number, Value: ".5", Pos: at(0, 1, 1), End: at(2, 1, 3)},
+				{Type: calc.Operator, Value: "+", Pos: at(2, 1, 3), End: at(3, 1, 4)},
+				{Type: calc.Number, Value: "5.", Pos: at(3, 1, 4), End: at(5, 1, 6)},
+			},
+		},
+		{
+			name:  "e starting a word",
+			input: "2e*2ex",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "2", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Constant, Value: "E", Pos: at(1, 1, 2), End: at(2, 1, 3)},
+				{Type: calc.Operator, Value: "*", Pos: at(2, 1, 3), End: at(3, 1, 4)},
+				{Type: calc.Number, Value: "2", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Constant, Value: "EX", Pos: at(4, 1, 5), End: at(6, 1, 7)},
+			},
+		},
+		{
+			name:  "special floats disabled",
+			input: "inf",
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "INF", Pos: at(0, 1, 1), End: at(3, 1, 4)},
+			},
+		},
+		{
+			name:  "special floats",
+			input: "inf-NaN",
+			opts:  calc.ScanOptions{SpecialFloats: true},
+			want: []calc.Token{
+				{Type: calc.Number, Value: "INF", Pos: at(0, 1, 1), End: at(3, 1, 4)},
+				{Type: calc.Operator, Value: "-", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Number, Value: "NAN", Pos: at(4, 1, 5), End: at(7, 1, 8)},
+			},
+		},
+		{
+			name:  "identifiers with underscores and dots",
+			input: "room_1.width*_x",
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "ROOM_1.WIDTH", Pos: at(0, 1, 1), End: at(12, 1, 13)},
+				{Type: calc.Operator, Value: "*", Pos: at(12, 1, 13), End: at(13, 1, 14)},
+				{Type: calc.Constant, Value: "_X", Pos: at(13, 1, 14), End: at(15, 1, 16)},
+			},
+		},
+		{
+			name:  "case sensitive",
+			input: "dT xor sin(Pi)",
+			opts:  calc.ScanOptions{CaseSensitive: true},
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "dT", Pos: at(0, 1, 1), End: at(2, 1, 3)},
+				{Type: calc.Whitespace, Value: " ", Pos: at(2, 1, 3), End: at(3, 1, 4)},
+				{Type: calc.Operator, Value: "XOR", Pos: at(3, 1, 4), End: at(6, 1, 7)},
+				{Type: calc.Whitespace, Value: " ", Pos: at(6, 1, 7), End: at(7, 1, 8)},
+				{Type: calc.Function, Value: "SIN", Pos: at(7, 1, 8), End: at(10, 1, 11)},
+				{Type: calc.Lparen, Value: "(", Pos: at(10, 1, 11), End: at(11, 1, 12)},
+				{Type: calc.Constant, Value: "Pi", Pos: at(11, 1, 12), End: at(13, 1, 14)},
+				{Type: calc.Rparen, Value: ")", Pos: at(13, 1, 14), End: at(14, 1, 15)},
+			},
+		},
+		{
+			name:  "custom identifier rule",
+			input: "$ab $c",
+			opts: calc.ScanOptions{IsIdentRune: func(ch rune, i int) bool {
+				return ch == '$' && i == 0 || unicode.IsLetter(ch) && i > 0
+			}},
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "$AB", Pos: at(0, 1, 1), End: at(3, 1, 4)},
+				{Type: calc.Whitespace, Value: " ", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Constant, Value: "$C", Pos: at(4, 1, 5), End: at(6, 1, 7)},
+			},
+		},
+		{
+			name:  "statements",
+			input: "x=1;x",
+			want: []calc.Token{
+				{Type: calc.Constant, Value: "X", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Operator, Value: "=", Pos: at(1, 1, 2), End: at(2, 1, 3)},
+				{Type: calc.Number, Value: "1", Pos: at(2, 1, 3), End: at(3, 1, 4)},
+				{Type: calc.Semicolon, Value: ";", Pos: at(3, 1, 4), End: at(4, 1, 5)},
+				{Type: calc.Constant, Value: "X", Pos: at(4, 1, 5), End: at(5, 1, 6)},
+			},
+		},
+		{
+			name:  "number followed by word",
+			input: "2in",
+			want: []calc.Token{
+				{Type: calc.Number, Value: "2", Pos: at(0, 1, 1), End: at(1, 1, 2)},
+				{Type: calc.Constant, Value: "IN", Pos: at(1, 1, 2), End: at(3, 1, 4)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calc.NewScannerOptions(strings.NewReader(tt.input), tt.opts)
+
+			var got []calc.Token
+			for {
+				tok, err := s.Scan()
+				if errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				got = append(got, tok)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanner_ScanInvalidNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantPos calc.Position
+		wantMsg string
+	}{
+		{name: "second decimal point", input: "2.243.4", wantPos: calc.Position{Offset: 5, Line: 1, Column: 6}, wantMsg: `1:6: invalid number "2.243": unexpected '.'`},
+		{name: "exponent without digits", input: "1 + 2e+", wantPos: calc.Position{Offset: 7, Line: 1, Column: 8}, wantMsg: `1:8: invalid number "2e+": exponent has no digits`},
+		{name: "decimal point in exponent", input: "1e5.5", wantPos: calc.Position{Offset: 3, Line: 1, Column: 4}, wantMsg: `1:4: invalid number "1e5": unexpected '.'`},
+		{name: "double underscore", input: "1__000", wantPos: calc.Position{Offset: 1, Line: 1, Column: 2}, wantMsg: `1:2: invalid number "1_": '_' must separate digits`},
+		{name: "trailing underscore", input: "10_", wantPos: calc.Position{Offset: 2, Line: 1, Column: 3}, wantMsg: `1:3: invalid number "10_": '_' must separate digits`},
+		{name: "invalid binary digit", input: "0b102", wantPos: calc.Position{Offset: 4, Line: 1, Column: 5}, wantMsg: `1:5: invalid digit '2' in binary number`},
+		{name: "prefix without digits", input: "0x", wantPos: calc.Position{Offset: 0, Line: 1, Column: 1}, wantMsg: `1:1: invalid number "0x": hexadecimal number has no digits`},
+		{name: "non-ASCII digit", input: "٣", wantPos: calc.Position{Offset: 0, Line: 1, Column: 1}, wantMsg: `1:1: invalid number "٣": only the digits 0-9 are allowed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calc.NewScanner(strings.NewReader(tt.input))
+
+			var err error
+			for err == nil {
+				_, err = s.Scan()
+			}
+
+			var syntaxErr *calc.SyntaxError
+			if !errors.As(err, &syntaxErr) || syntaxErr.Kind != calc.InvalidNumber {
+				t.Fatalf("Scan() error = %#v, want InvalidNumber", err)
+			}
+			if syntaxErr.Pos != tt.wantPos {
+				t.Errorf("Scan() error position = %v, want %v", syntaxErr.Pos, tt.wantPos)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("Scan() error = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ScriptError is returned by EvalScript if a statement fails.
+type ScriptError struct {
+	// Statement is the number of the failed statement, starting at 1.
+	Statement int
+	// Pos is the position of the first token of the statement.
+	Pos Position
+	// Err is the *SyntaxError or *EvalError of the statement.
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement %d in line %d: %v", e.Statement, e.Pos.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultMaxCallDepth is the maximum depth of nested calls of functions
+// defined by a script if ScriptOptions.MaxCallDepth is not set.
+const DefaultMaxCallDepth = 1000
+
+// ScriptOptions configures a Script and EvalScriptOptions.
+type ScriptOptions struct {
+	// MaxCallDepth limits how deep calls of functions defined by the script
+	// may be nested, which stops endless recursion.
+	// Zero or a negative value means DefaultMaxCallDepth.
+	MaxCallDepth int
+}
+
+// Script evaluates scripts one after another and keeps the variables and
+// functions they define, so that later scripts can use them, like in a REPL.
+// The zero value is a Script without variables and with the default options.
+type Script struct {
+	// Vars are the variables, which are changed by assignments.
+	Vars Env
+
+	opts  ScriptOptions
+	funcs map[string]*userFunc
+	// runs counts the calls of Eval, see userFunc.run.
+	runs int
+}
+
+// NewScript returns a Script whose variables start as a copy of env,
+// which is not modified.
+func NewScript(env Env, opts ScriptOptions) *Script {
+	vars := make(Env, len(env))
+	for name, val := range env {
+		vars[name] = val
+	}
+	return &Script{Vars: vars, opts: opts}
+}
+
+// Result is the result of a statement of a script.
+type Result struct {
+	// Value is the value of an expression or an assignment.
+	// It is zero for a function definition.
+	Value float64
+	// Name is the assigned variable or the defined function.
+	// It is empty for an expression.
+	Name string
+	// Func reports whether the statement defines the function Name.
+	Func bool
+}
+
+// Eval evaluates the statements read from r like EvalScript and returns
+// the result of the last one. The statements can use the variables and
+// call the functions of the earlier calls of Eval.
+//
+// Errors are returned as *ScriptError. The statements before the failed one
+// keep their effect.
+func (s *Script) Eval(r io.Reader) (Result, error) {
+	res, _, err := s.eval(r)
+	return res, err
+}
+
+// Funcs returns the names of the functions defined by the scripts in
+// alphabetical order.
+func (s *Script) Funcs() []string {
+	names := make([]string, 0, len(s.funcs))
+	for name := range s.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Definition returns the definition of the function name, such as
+// F(X, Y) = ((X ^ 2) + (Y ^ 2)), or false if the scripts did not define it.
+func (s *Script) Definition(name string) (string, bool) {
+	f, ok := s.funcs[name]
+	if !ok {
+		return "", false
+	}
+	return f.String(), true
+}
+
+// EvalScript evaluates a script of several statements and returns the value
+// of the last statement together with the variables after the script.
+//
+// The statements are separated by ";" or by line breaks outside of
+// parentheses. A statement is either an expression, an assignment such as
+// x = 3*4, whose value is the assigned value, or a function definition such
+// as f(x, y) = x^2 + y^2. The variables start as a copy of env, which is not
+// modified.
+//
+// Errors are returned as *ScriptError, which contains the number of the
+// failed statement.
+func EvalScript(r io.Reader, env Env) (float64, Env, error) {
+	return EvalScriptOptions(r, env, ScriptOptions{})
+}
+
+// EvalScriptOptions evaluates a script like EvalScript using the configuration opts.
+//
+// Functions defined by the script can be called by the following statements
+// and by themselves. They are called instead of a built-in function with the
+// same name. Their body sees the parameters and the variables at the time of
+// the definition, so later assignments do not change the function.
+// A definition has no value, so a script ending with one returns the value
+// of the statement before it.
+//
+// Use a Script to keep the functions for later scripts.
+func EvalScriptOptions(r io.Reader, env Env, opts ScriptOptions) (float64, Env, error) {
+	s := NewScript(env, opts)
+	_, val, err := s.eval(r)
+	return val, s.Vars, err
+}
+
+// eval evaluates a script and returns the result of the last statement and
+// the value of the last statement which has one.
+func (s *Script) eval(r io.Reader) (last Result, val float64, err error) {
+	if s.Vars == nil {
+		s.Vars = Env{}
+	}
+	if s.funcs == nil {
+		s.funcs = map[string]*userFunc{}
+	}
+	maxCallDepth := s.opts.MaxCallDepth
+	if maxCallDepth <= 0 {
+		maxCallDepth = DefaultMaxCallDepth
+	}
+	s.runs++
+	e := &evaluator{vars: s.Vars, funcs: s.funcs, maxCallDepth: maxCallDepth, run: s.runs}
+
+	sc := NewScanner(r)
+	var (
+		statement  []Token
+		statements int
+		depth      int
+	)
+	for {
+		tok, err := sc.Scan()
+		end := errors.Is(err, io.EOF)
+		if err != nil && !end {
+			pos := ErrorPosition(err)
+			if len(statement) > 0 {
+				pos = statement[0].Pos
+			}
+			return Result{}, 0, &ScriptError{Statement: statements + 1, Pos: pos, Err: err}
+		}
+
+		if !end && tok.Type != Semicolon && (tok.Type != Whitespace || depth > 0 || !strings.Contains(tok.Value, "\n")) {
+			switch tok.Type {
+			case Lparen:
+				depth++
+			case Rparen:
+				depth--
+			case Whitespace:
+				continue
+			}
+			statement = append(statement, tok)
+			continue
+		}
+
+		if len(statement) > 0 {
+			statements++
+			res, err := e.statement(statement)
+			if err != nil {
+				return Result{}, 0, &ScriptError{Statement: statements, Pos: statement[0].Pos, Err: err}
+			}
+			last = res
+			if !res.Func {
+				val = res.Value
+			}
+		}
+		statement, depth = nil, 0
+		if end {
+			break
+		}
+	}
+
+	if statements == 0 {
+		return Result{}, 0, &SyntaxError{Kind: EmptyExpression, Msg: "empty script"}
+	}
+	return last, val, nil
+}
+
+// userFunc is a function defined by a script.
+type userFunc struct {
+	name   string
+	params []string
+	body   Node
+	// env are the variables at the time of the definition.
+	env Env
+	// run is the call of Script.Eval which defined the function. The
+	// positions in body are only valid for errors of the same call.
+	run int
+}
+
+func (f *userFunc) String() string {
+	return f.name + "(" + strings.Join(f.params, ", ") + ") = " + f.body.String()
+}
+
+// statement evaluates the tokens of a single statement of a script.
+func (e *evaluator) statement(tokens []Token) (Result, error) {
+	if end := definitionEnd(tokens); end > 0 {
+		if end == len(tokens)-1 {
+			return Result{}, missingValue(tokens[end])
+		}
+		return Result{Name: strings.ToUpper(tokens[0].Value), Func: true}, e.define(tokens[:end], tokens[end+1:])
+	}
+
+	var name string
+	if len(tokens) > 1 && tokens[0].Type == Constant && tokens[1].Type == Operator && tokens[1].Value == "=" {
+		if len(tokens) == 2 {
+			return Result{}, missingValue(tokens[1])
+		}
+		name = tokens[0].Value
+		tokens = tokens[2:]
+	}
+
+	tree, err := parse(&Parser{s: &tokenSlice{tokens: tokens}})
+	if err != nil {
+		return Result{}, err
+	}
+	val, err := e.eval(tree)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if name != "" {
+		e.vars[name] = val
+	}
+	return Result{Value: val, Name: name}, nil
+}
+
+// missingValue returns the error for an assignment or definition which ends
+// with the "=" token eq.
+func missingValue(eq Token) error {
+	return &SyntaxError{Kind: EmptyExpression, Pos: eq.End, Token: eq, Msg: "missing value after ="}
+}
+
+// definitionEnd returns the index of the "=" if the tokens are a function
+// definition such as F(X, Y) = X^2 + Y^2 or F (X) = 2*X, otherwise 0.
+func definitionEnd(tokens []Token) int {
+	if len(tokens) < 2 || tokens[1].Type != Lparen {
+		return 0
+	}
+	if name := tokens[0]; name.Type != Function && (name.Type != Constant || name.End.Line != tokens[1].Pos.Line) {
+		return 0
+	}
+
+	for i, tok := range tokens[2:] {
+		if tok.Type == Rparen {
+			end := i + 3
+			if end < len(tokens) && tokens[end].Type == Operator && tokens[end].Value == "=" {
+				return end
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// define adds the function defined by the head F(X, Y) and the tokens of its body.
+func (e *evaluator) define(head, body []Token) error {
+	var params []string
+	for i, tok := range head[2 : len(head)-1] {
+		if i%2 == 1 {
+			if tok.Type != Comma {
+				return &SyntaxError{Kind: UnexpectedToken, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("expected ',' instead of %s", tok.Value)}
+			}
+			continue
+		}
+
+		if tok.Type != Constant {
+			return &SyntaxError{Kind: UnexpectedToken, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("invalid parameter %s", tok.Value)}
+		}
+		for _, param := range params {
+			if param == tok.Value {
+				return &SyntaxError{Kind: UnexpectedToken, Pos: tok.Pos, Token: tok, Msg: fmt.Sprintf("duplicate parameter %s", tok.Value)}
+			}
+		}
+		params = append(params, tok.Value)
+	}
+	if last := head[len(head)-2]; last.Type == Comma {
+		return &SyntaxError{Kind: MissingOperand, Pos: last.Pos, Token: last, Msg: "missing parameter after ','"}
+	}
+
+	tree, err := parse(&Parser{s: &tokenSlice{tokens: body}})
+	if err != nil {
+		return err
+	}
+
+	env := make(Env, len(e.vars))
+	for name, val := range e.vars {
+		env[name] = val
+	}
+	name := strings.ToUpper(head[0].Value)
+	e.funcs[name] = &userFunc{name: name, params: params, body: tree, env: env, run: e.run}
+	return nil
+}
+
+// tokenSlice is a TokenScanner which returns already scanned tokens.
+type tokenSlice struct {
+	tokens []Token
+}
+
+func (s *tokenSlice) Scan() (Token, error) {
+	if len(s.tokens) == 0 {
+		return Token{}, io.EOF
+	}
+	tok := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return tok, nil
+}
@@ -0,0 +1,275 @@
+package calc_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aligator/calc"
+)
+
+func TestEvalScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		env      calc.Env
+		want     float64
+		wantEnv  calc.Env
+		wantErr  string
+		wantStmt int
+	}{
+		{name: "single expression", input: "1+2", want: 3, wantEnv: calc.Env{}},
+		{name: "semicolons", input: "a = 5; b = a*2; b^2", want: 100, wantEnv: calc.Env{"A": 5, "B": 10}},
+		{name: "lines", input: "a = 5\n\nb = a*2\nb^2\n", want: 100, wantEnv: calc.Env{"A": 5, "B": 10}},
+		{name: "assignment as last statement", input: "x = 3*4;", want: 12, wantEnv: calc.Env{"X": 12}},
+		{name: "line break inside parentheses", input: "x = (1 +\n  2)\nx", want: 3, wantEnv: calc.Env{"X": 3}},
+		{name: "with env", input: "y = x + 1", env: calc.Env{"X": 1}, want: 2, wantEnv: calc.Env{"X": 1, "Y": 2}},
+		{name: "reassignment", input: "x = 1; x = x + 1; x", want: 2, wantEnv: calc.Env{"X": 2}},
+		{name: "comparison", input: "x = 2; x == 2", want: 1, wantEnv: calc.Env{"X": 2}},
+		{name: "empty script", input: " ;\n ", wantErr: "empty script"},
+		{
+			name:     "evaluation error",
+			input:    "a = 1\nb = a / 0\nb",
+			wantErr:  "statement 2 in line 2: 2:7: division by zero: 1 / 0",
+			wantStmt: 2,
+		},
+		{
+			name:     "syntax error",
+			input:    "1; 2 +",
+			wantErr:  "statement 2 in line 1: 1:6: missing operand for '+'",
+			wantStmt: 2,
+		},
+		{
+			name:     "scan error",
+			input:    "1\n2 $ 3",
+			wantErr:  "statement 2 in line 2: 2:3: invalid token '$'",
+			wantStmt: 2,
+		},
+		{
+			name:     "assignment without value",
+			input:    "x =",
+			wantErr:  "statement 1 in line 1: 1:4: missing value after =",
+			wantStmt: 1,
+		},
+		{
+			name:     "assignment inside an expression",
+			input:    "1 + x = 2",
+			wantErr:  "statement 1 in line 1: 1:7: unexpected operator =",
+			wantStmt: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotEnv, err := calc.EvalScript(strings.NewReader(tt.input), tt.env)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("EvalScript() error = %v, want %v", err, tt.wantErr)
+				}
+				var scriptErr *calc.ScriptError
+				if errors.As(err, &scriptErr) && scriptErr.Statement != tt.wantStmt {
+					t.Errorf("EvalScript() statement = %v, want %v", scriptErr.Statement, tt.wantStmt)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("EvalScript() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalScript() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotEnv, tt.wantEnv) {
+				t.Errorf("EvalScript() env = %v, want %v", gotEnv, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func TestEvalScript_KeepsEnv(t *testing.T) {
+	env := calc.Env{"X": 1}
+	if _, _, err := calc.EvalScript(strings.NewReader("x = 2"), env); err != nil {
+		t.Fatalf("EvalScript() error = %v", err)
+	}
+	if env["X"] != 1 {
+		t.Errorf("EvalScript() changed env to %v", env)
+	}
+}
+
+func TestEvalScript_Functions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    calc.ScriptOptions
+		want    float64
+		wantErr string
+	}{
+		{name: "definition and call", input: "f(x, y) = x^2 + y^2\nf(3, 4)", want: 25},
+		{name: "without parameters", input: "answer() = 42; answer() + 1", want: 43},
+		{name: "space before parameters", input: "f (x) = 2*x; f (3)", want: 6},
+		{name: "definition has no value", input: "1 + 2; f(x) = x", want: 3},
+		{name: "nested calls", input: "sq(x) = x*x; sum(a, b) = sq(a) + sq(b); sum(sq(1), 2)", want: 5},
+		{name: "shadows built-in", input: "sqrt(x) = x; sqrt(16)", want: 16},
+		{name: "parameter shadows variable", input: "x = 10; f(x) = x + 1; f(1) + x", want: 12},
+		{name: "closure over defining variables", input: "k = 2; f(x) = k*x; k = 100; f(3)", want: 6},
+		{name: "recursion", input: "fact(n) = n <= 1 ? 1 : n * fact(n - 1); fact(10)", want: 3628800},
+		{name: "mutual recursion", input: "even(n) = n == 0 ? 1 : odd(n - 1); odd(n) = n == 0 ? 0 : even(n - 1); even(10)", want: 1},
+		{name: "redefinition", input: "f(x) = x; f(x) = 2*x; f(2)", want: 4},
+		{
+			name:    "too few arguments",
+			input:   "f(x, y) = x + y; f(1)",
+			wantErr: "statement 2 in line 1: 1:18: function F expects 2 argument(s), got 1",
+		},
+		{
+			name:    "too many arguments",
+			input:   "f() = 1; f(1)",
+			wantErr: "statement 2 in line 1: 1:10: function F expects 0 argument(s), got 1",
+		},
+		{
+			name:    "endless recursion",
+			input:   "f(x) = f(x + 1); f(0)",
+			wantErr: "statement 2 in line 1: 1:8: calls of F are nested deeper than 1000 levels",
+		},
+		{
+			name:    "recursion depth option",
+			input:   "fact(n) = n <= 1 ? 1 : n * fact(n - 1); fact(10)",
+			opts:    calc.ScriptOptions{MaxCallDepth: 5},
+			wantErr: "statement 2 in line 1: 1:28: calls of FACT are nested deeper than 5 levels",
+		},
+		{
+			name:    "error in body",
+			input:   "f(x) = 1 / x\nf(0)",
+			wantErr: "statement 2 in line 2: 1:10: division by zero: 1 / 0",
+		},
+		{
+			name:    "unknown variable in body",
+			input:   "f(x) = x + y; f(1)",
+			wantErr: "statement 2 in line 1: 1:12: undefined variable Y",
+		},
+		{
+			name:    "invalid parameter",
+			input:   "f(1) = 2",
+			wantErr: "statement 1 in line 1: 1:3: invalid parameter 1",
+		},
+		{
+			name:    "duplicate parameter",
+			input:   "f(x, x) = 2",
+			wantErr: "statement 1 in line 1: 1:6: duplicate parameter X",
+		},
+		{
+			name:    "missing comma",
+			input:   "f(x y) = 2",
+			wantErr: "statement 1 in line 1: 1:5: expected ',' instead of Y",
+		},
+		{
+			name:    "trailing comma",
+			input:   "f(x,) = 2",
+			wantErr: "statement 1 in line 1: 1:4: missing parameter after ','",
+		},
+		{
+			name:    "missing body",
+			input:   "f(x) =",
+			wantErr: "statement 1 in line 1: 1:7: missing value after =",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := calc.EvalScriptOptions(strings.NewReader(tt.input), nil, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("EvalScriptOptions() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("EvalScriptOptions() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalScriptOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalScript_FunctionEnv(t *testing.T) {
+	_, env, err := calc.EvalScript(strings.NewReader("f(x) = x; y = f(2)"), nil)
+	if err != nil {
+		t.Fatalf("EvalScript() error = %v", err)
+	}
+	if want := (calc.Env{"Y": 2}); !reflect.DeepEqual(env, want) {
+		t.Errorf("EvalScript() env = %v, want %v", env, want)
+	}
+}
+
+func TestScript(t *testing.T) {
+	s := calc.NewScript(calc.Env{"K": 2}, calc.ScriptOptions{})
+	steps := []struct {
+		input   string
+		want    calc.Result
+		wantErr string
+	}{
+		{input: "f(x, y) = k*x + y", want: calc.Result{Name: "F", Func: true}},
+		{input: "f(3, 4)", want: calc.Result{Value: 10}},
+		{input: "k = 100; a = f(1, 0)", want: calc.Result{Value: 2, Name: "A"}},
+		{input: "fact(n) = n <= 1 ? 1 : n * fact(n - 1)", want: calc.Result{Name: "FACT", Func: true}},
+		{input: "fact(5) + a", want: calc.Result{Value: 122}},
+		{input: "g(x) = 1 / x", want: calc.Result{Name: "G", Func: true}},
+		{input: "(1 +\n g(0))", wantErr: "statement 1 in line 1: 2:2: division by zero: 1 / 0"},
+		{input: "f(1)", wantErr: "statement 1 in line 1: 1:1: function F expects 2 argument(s), got 1"},
+	}
+	for _, step := range steps {
+		got, err := s.Eval(strings.NewReader(step.input))
+		if step.wantErr != "" {
+			if err == nil || err.Error() != step.wantErr {
+				t.Errorf("Eval(%q) error = %v, want %v", step.input, err, step.wantErr)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Eval(%q) error = %v", step.input, err)
+		}
+		if got != step.want {
+			t.Errorf("Eval(%q) = %+v, want %+v", step.input, got, step.want)
+		}
+	}
+
+	if want := (calc.Env{"K": 100, "A": 2}); !reflect.DeepEqual(s.Vars, want) {
+		t.Errorf("Vars = %v, want %v", s.Vars, want)
+	}
+	if got, want := s.Funcs(), []string{"F", "FACT", "G"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Funcs() = %v, want %v", got, want)
+	}
+	if got, ok := s.Definition("F"); !ok || got != "F(X, Y) = ((K * X) + Y)" {
+		t.Errorf("Definition() = %q, %v", got, ok)
+	}
+	if _, ok := s.Definition("SIN"); ok {
+		t.Errorf("Definition() of a built-in function should not exist")
+	}
+}
+
+func TestScript_ZeroValue(t *testing.T) {
+	var s calc.Script
+	if _, err := s.Eval(strings.NewReader("f(x) = x + 1")); err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	got, err := s.Eval(strings.NewReader("y = f(1)"))
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if got.Value != 2 || s.Vars["Y"] != 2 {
+		t.Errorf("Eval() = %+v, Vars = %v", got, s.Vars)
+	}
+}
+
+func TestScript_NegativeMaxCallDepth(t *testing.T) {
+	s := calc.NewScript(nil, calc.ScriptOptions{MaxCallDepth: -1})
+	got, err := s.Eval(strings.NewReader("f(n) = n <= 0 ? 0 : 1 + f(n - 1); f(500)"))
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if got.Value != 500 {
+		t.Errorf("Eval() = %v, want 500", got.Value)
+	}
+}
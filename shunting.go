@@ -1,50 +1,130 @@
 package calc
 
-import (
-	"fmt"
-)
+import "fmt"
+
+// paren is an opened parenthesis which is not yet closed.
+type paren struct {
+	// tok is the opening parenthesis.
+	tok Token
+	// call is true if the parenthesis contains the arguments of a function call.
+	call bool
+	// commas is the amount of commas found directly inside the parenthesis.
+	commas int
+}
 
 func ShuntingYard(s Stack) (Stack, error) {
-	parenthesisCounter := 0
+	var parens []paren
 
 	postfix := Stack{}
 	operators := Stack{}
-	for _, v := range s {
+	for i, v := range s {
 		switch v.Type {
 		case Operator:
-			for !operators.IsEmpty() {
+			if v.Value == ":" {
+				if err := closeConditional(&operators, &postfix, v); err != nil {
+					return postfix, err
+				}
+				continue
+			}
+
+			for !operators.IsEmpty() && (operators.Peek().Type == Operator || operators.Peek().Type == UnaryOperator) {
 				val := v.Value
-				top := operators.Peek().Value
-				if (oprData[val].prec <= oprData[top].prec && oprData[val].rAsoc == false) ||
-					(oprData[val].prec < oprData[top].prec && oprData[val].rAsoc == true) {
+				top := precedence(operators.Peek())
+				if (oprData[val].prec <= top && oprData[val].rAsoc == false) ||
+					(oprData[val].prec < top && oprData[val].rAsoc == true) {
 					postfix.Push(operators.Pop())
 					continue
 				}
 				break
 			}
 			operators.Push(v)
+		case PostfixOperator:
+			// A postfix operator binds stronger than every other operator,
+			// so its operand is already complete.
+			postfix.Push(v)
+		case UnaryOperator:
+			// A prefix operator has no operand yet, so nothing can be popped for it.
+			operators.Push(v)
+		case Function:
+			operators.Push(v)
 		case Lparen:
-			parenthesisCounter++
+			parens = append(parens, paren{tok: v, call: i > 0 && s[i-1].Type == Function})
 			operators.Push(v)
+		case Comma:
+			if len(parens) == 0 || !parens[len(parens)-1].call {
+				return postfix, &SyntaxError{Kind: UnexpectedToken, Pos: v.Pos, Token: v, Msg: "comma outside of a function call"}
+			}
+			if i+1 < len(s) && (s[i+1].Type == Rparen || s[i+1].Type == Comma) {
+				return postfix, &SyntaxError{Kind: MissingOperand, Pos: v.Pos, Token: v, Msg: "missing argument after ','"}
+			}
+			parens[len(parens)-1].commas++
+
+			for operators.Peek().Type != Lparen {
+				postfix.Push(operators.Pop())
+			}
 		case Rparen:
-			for i := operators.Length() - 1; i >= 0; i-- {
-				if operators[i].Type != Lparen {
-					postfix.Push(operators.Pop())
-					continue
-				} else {
-					operators.Pop()
-					break
+			if len(parens) == 0 {
+				return postfix, &SyntaxError{Kind: UnbalancedParen, Pos: v.Pos, Token: v, Msg: "closing parenthesis without opening one"}
+			}
+			p := parens[len(parens)-1]
+			parens = parens[:len(parens)-1]
+			if !p.call && s[i-1].Type == Lparen {
+				return postfix, &SyntaxError{Kind: EmptyExpression, Pos: p.tok.Pos, Token: p.tok, Msg: "empty parentheses"}
+			}
+
+			for operators.Peek().Type != Lparen {
+				postfix.Push(operators.Pop())
+			}
+			operators.Pop()
+
+			if p.call {
+				function := operators.Pop()
+				function.Args = p.commas
+				// Only an empty call like "F()" has no argument after the last comma.
+				if s[i-1].Type != Lparen {
+					function.Args++
 				}
+				postfix.Push(function)
 			}
-			parenthesisCounter--
 		default:
 			postfix.Push(v)
 		}
 	}
-	operators.EmptyInto(&postfix)
 
-	if parenthesisCounter != 0 {
-		return postfix, fmt.Errorf("invalid parenthesis count - %d do not match", parenthesisCounter)
+	if len(parens) != 0 {
+		return postfix, &SyntaxError{
+			Kind:  UnbalancedParen,
+			Pos:   parens[len(parens)-1].tok.Pos,
+			Token: parens[len(parens)-1].tok,
+			Msg:   fmt.Sprintf("%d parenthesis not closed", len(parens)),
+		}
 	}
+	operators.EmptyInto(&postfix)
+
 	return postfix, nil
 }
+
+// closeConditional handles the ":" of a conditional operator.
+// It moves all operators of the then-branch to the postfix notation and
+// replaces the matching "?" by "?:", which takes three operands.
+func closeConditional(operators, postfix *Stack, colon Token) error {
+	for !operators.IsEmpty() && (operators.Peek().Type == Operator || operators.Peek().Type == UnaryOperator) && operators.Peek().Value != "?" {
+		postfix.Push(operators.Pop())
+	}
+	if operators.IsEmpty() || operators.Peek().Value != "?" {
+		return &SyntaxError{Kind: UnexpectedToken, Pos: colon.Pos, Token: colon, Msg: "':' without matching '?'"}
+	}
+
+	cond := operators.Pop()
+	cond.Value = "?:"
+	operators.Push(cond)
+	return nil
+}
+
+// precedence returns the precedence of an operator token.
+func precedence(tok Token) int {
+	if tok.Type == UnaryOperator {
+		return unaryPrec[tok.Value]
+	}
+	return oprData[tok.Value].prec
+}
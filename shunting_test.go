@@ -40,6 +40,42 @@ func TestShuntingYard(t *testing.T) {
 				{Type: calc.Operator, Value: "-"},
 			},
 		},
+		{
+			name: "unary operators",
+			input: calc.Stack{ // -2^-x * 3
+				{Type: calc.UnaryOperator, Value: "-"},
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Operator, Value: "^"},
+				{Type: calc.UnaryOperator, Value: "-"},
+				{Type: calc.Constant, Value: "X"},
+				{Type: calc.Operator, Value: "*"},
+				{Type: calc.Number, Value: "3"},
+			},
+			want: calc.Stack{
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Constant, Value: "X"},
+				{Type: calc.UnaryOperator, Value: "-"},
+				{Type: calc.Operator, Value: "^"},
+				{Type: calc.UnaryOperator, Value: "-"},
+				{Type: calc.Number, Value: "3"},
+				{Type: calc.Operator, Value: "*"},
+			},
+		},
+		{
+			name: "postfix operator",
+			input: calc.Stack{ // 2^3!
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Operator, Value: "^"},
+				{Type: calc.Number, Value: "3"},
+				{Type: calc.PostfixOperator, Value: "!"},
+			},
+			want: calc.Stack{
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Number, Value: "3"},
+				{Type: calc.PostfixOperator, Value: "!"},
+				{Type: calc.Operator, Value: "^"},
+			},
+		},
 		{
 			name: "with several matching Parentheses",
 			input: calc.Stack{ // (( 1 + 2 * (77 + 55)) + 3)
@@ -115,6 +151,62 @@ func TestShuntingYard(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "with function calls",
+			input: calc.Stack{ // MAX(1, MIN(2, 3) * 4, F()) + 5
+				{Type: calc.Function, Value: "MAX"},
+				{Type: calc.Lparen, Value: "("},
+				{Type: calc.Number, Value: "1"},
+				{Type: calc.Comma, Value: ","},
+				{Type: calc.Function, Value: "MIN"},
+				{Type: calc.Lparen, Value: "("},
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Comma, Value: ","},
+				{Type: calc.Number, Value: "3"},
+				{Type: calc.Rparen, Value: ")"},
+				{Type: calc.Operator, Value: "*"},
+				{Type: calc.Number, Value: "4"},
+				{Type: calc.Comma, Value: ","},
+				{Type: calc.Function, Value: "F"},
+				{Type: calc.Lparen, Value: "("},
+				{Type: calc.Rparen, Value: ")"},
+				{Type: calc.Rparen, Value: ")"},
+				{Type: calc.Operator, Value: "+"},
+				{Type: calc.Number, Value: "5"},
+			},
+			want: calc.Stack{
+				{Type: calc.Number, Value: "1"},
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Number, Value: "3"},
+				{Type: calc.Function, Value: "MIN", Args: 2},
+				{Type: calc.Number, Value: "4"},
+				{Type: calc.Operator, Value: "*"},
+				{Type: calc.Function, Value: "F", Args: 0},
+				{Type: calc.Function, Value: "MAX", Args: 3},
+				{Type: calc.Number, Value: "5"},
+				{Type: calc.Operator, Value: "+"},
+			},
+		},
+		{
+			name: "with comma outside of a function call",
+			input: calc.Stack{ // (1, 2)
+				{Type: calc.Lparen, Value: "("},
+				{Type: calc.Number, Value: "1"},
+				{Type: calc.Comma, Value: ","},
+				{Type: calc.Number, Value: "2"},
+				{Type: calc.Rparen, Value: ")"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "with unclosed function call",
+			input: calc.Stack{ // COS(1
+				{Type: calc.Function, Value: "COS"},
+				{Type: calc.Lparen, Value: "("},
+				{Type: calc.Number, Value: "1"},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -122,9 +214,11 @@ func TestShuntingYard(t *testing.T) {
 
 			if err != nil && !tt.wantErr {
 				t.Errorf("ShuntingYard() got error %v, want nil", err)
+				return
 			} else if tt.wantErr && err == nil {
 				t.Error("ShuntingYard() got no error, want non-nil")
-			} else {
+				return
+			} else if tt.wantErr {
 				return
 			}
 
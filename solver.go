@@ -1,7 +1,7 @@
 package calc
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"math"
 	"strconv"
@@ -9,16 +9,69 @@ import (
 	"unicode"
 )
 
+// oprData contains the binary operators.
+// Comparisons and logical operators return 1 for true and 0 for false,
+// every value except 0 is true.
+// Bitwise operators bind stronger than comparisons, so x & 1 == 1 is (x & 1) == 1.
+// "?" and "?:" are the conditional operator before and after its ":" is found.
 var oprData = map[string]struct {
 	prec  int
 	rAsoc bool // true = right // false = left
 	fx    func(x, y float64) float64
 }{
-	"^": {4, true, func(x, y float64) float64 { return math.Pow(x, y) }},
-	"*": {3, false, func(x, y float64) float64 { return x * y }},
-	"/": {3, false, func(x, y float64) float64 { return x / y }},
-	"+": {2, false, func(x, y float64) float64 { return x + y }},
-	"-": {2, false, func(x, y float64) float64 { return x - y }},
+	"^":   {13, true, func(x, y float64) float64 { return math.Pow(x, y) }},
+	"*":   {11, false, func(x, y float64) float64 { return x * y }},
+	"/":   {11, false, func(x, y float64) float64 { return x / y }},
+	"//":  {11, false, func(x, y float64) float64 { return math.Floor(x / y) }},
+	"%":   {11, false, floorMod},
+	"+":   {10, false, func(x, y float64) float64 { return x + y }},
+	"-":   {10, false, func(x, y float64) float64 { return x - y }},
+	"<<":  {9, false, func(x, y float64) float64 { return math.Ldexp(x, int(y)) }},
+	">>":  {9, false, func(x, y float64) float64 { return math.Floor(math.Ldexp(x, -int(y))) }},
+	"&":   {8, false, func(x, y float64) float64 { return float64(int64(x) & int64(y)) }},
+	"XOR": {7, false, func(x, y float64) float64 { return float64(int64(x) ^ int64(y)) }},
+	"|":   {6, false, func(x, y float64) float64 { return float64(int64(x) | int64(y)) }},
+	"<":   {5, false, func(x, y float64) float64 { return boolToFloat(x < y) }},
+	"<=":  {5, false, func(x, y float64) float64 { return boolToFloat(x <= y) }},
+	">":   {5, false, func(x, y float64) float64 { return boolToFloat(x > y) }},
+	">=":  {5, false, func(x, y float64) float64 { return boolToFloat(x >= y) }},
+	"==":  {4, false, func(x, y float64) float64 { return boolToFloat(x == y) }},
+	"!=":  {4, false, func(x, y float64) float64 { return boolToFloat(x != y) }},
+	"&&":  {3, false, func(x, y float64) float64 { return boolToFloat(x != 0 && y != 0) }},
+	"||":  {2, false, func(x, y float64) float64 { return boolToFloat(x != 0 || y != 0) }},
+	"?":   {1, true, nil},
+	"?:":  {1, true, nil},
+}
+
+// unaryPrec is the precedence of the unary operators.
+// They bind weaker than "^", so -2^2 is -(2^2).
+var unaryPrec = map[string]int{
+	"+": 12,
+	"-": 12,
+	"!": 12,
+	"~": 12,
+}
+
+// postfixFuncs maps the postfix operators to the functions they call.
+var postfixFuncs = map[string]string{
+	"!": "FACT",
+}
+
+// floorMod returns the remainder of the floor division x // y,
+// which has the sign of y.
+func floorMod(x, y float64) float64 {
+	m := math.Mod(x, y)
+	if m != 0 && (m < 0) != (y < 0) {
+		m += y
+	}
+	return m
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
 }
 
 var funcs = map[string]func(x float64) float64{
@@ -52,69 +105,32 @@ var consts = map[string]float64{
 
 // SolvePostfix evaluates and returns the answer of the expression converted to postfix
 func SolvePostfix(tokens Stack) (float64, error) {
-	stack := Stack{}
-	for _, v := range tokens {
-		switch v.Type {
-		case Number:
-			stack.Push(v)
-		case Function:
-			res, err := SolveFunction(v.Value)
-			if err != nil {
-				return 0, err
-			}
-			stack.Push(Token{Number, res})
-		case Constant:
-			if val, ok := consts[v.Value]; ok {
-				stack.Push(Token{Number, strconv.FormatFloat(val, 'f', -1, 64)})
-			}
-		case Operator:
-			f := oprData[v.Value].fx
-			y, err := strconv.ParseFloat(stack.Pop().Value, 64)
-			if err != nil {
-				return 0, err
-			}
-			x, err := strconv.ParseFloat(stack.Pop().Value, 64)
-			if err != nil {
-				return 0, err
-			}
-
-			result := f(x, y)
-			stack.Push(Token{Number, strconv.FormatFloat(result, 'f', -1, 64)})
-		}
-	}
-
-	if len(stack) == 0 {
-		return 0, errors.New("empty stack - calculation could not be solved")
+	tree, err := buildTree(tokens)
+	if err != nil {
+		return 0, err
 	}
 
-	return strconv.ParseFloat(stack[0].Value, 64)
+	return Eval(tree)
 }
 
 // SolveFunction returns the answer of a function found within an expression
 func SolveFunction(s string) (string, error) {
-	var fArg float64
-	var err error
-
-	fType := s[:strings.Index(s, "(")]
-	args := s[strings.Index(s, "(")+1 : strings.LastIndex(s, ")")]
-	if !strings.ContainsAny(args, "+*-/^") && !ContainsLetter(args) {
-		fArg, err = strconv.ParseFloat(args, 64)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		fArg, err = Solve(args)
-		if err != nil {
-			return "", err
-		}
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return "", err
 	}
 
-	function, ok := funcs[fType]
+	call, ok := tree.(*Call)
 	if !ok {
-		return "", fmt.Errorf("function does not exist: %s", fType)
+		return "", fmt.Errorf("not a function call: %s", s)
+	}
+
+	res, err := Eval(call)
+	if err != nil {
+		return "", err
 	}
 
-	return strconv.FormatFloat(function(fArg), 'f', -1, 64), nil
+	return strconv.FormatFloat(res, 'f', -1, 64), nil
 }
 
 // ContainsLetter checks if a string contains a letter
@@ -129,15 +145,114 @@ func ContainsLetter(s string) bool {
 
 // Solve a mathematical calculation.
 func Solve(s string) (float64, error) {
-	stack, err := NewParser(strings.NewReader(s)).Parse()
+	return SolveWith(s, nil)
+}
+
+// SolveWith solves a mathematical calculation which may contain variables.
+func SolveWith(s string, vars Env) (float64, error) {
+	tree, err := ParseExpr(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return EvalWith(tree, vars)
+}
+
+// tokenLimiter is a TokenScanner which stops with a TooManyTokens error
+// after max tokens without whitespace.
+type tokenLimiter struct {
+	s     TokenScanner
+	max   int
+	count int
+}
+
+func (l *tokenLimiter) Scan() (Token, error) {
+	tok, err := l.s.Scan()
+	if err != nil || tok.Type == Whitespace {
+		return tok, err
+	}
+
+	l.count++
+	if l.count > l.max {
+		return Token{}, &LimitError{Kind: TooManyTokens, Limit: l.max, Pos: tok.Pos, Msg: fmt.Sprintf("expression has more than %d tokens", l.max)}
+	}
+	return tok, nil
+}
+
+// SolveOptions configures SolveContext.
+// The limits are disabled if they are 0.
+type SolveOptions struct {
+	// Parse configures the parser.
+	Parse ParseOptions
+	// Vars are the variables which can be used in the expression.
+	Vars Env
+	// Lenient evaluates unknown identifiers to 0. By default the evaluation
+	// is strict and fails with an UnknownIdentifier error, which suggests
+	// similar names.
+	Lenient bool
+	// Registry contains the functions which can be called.
+	// If it is nil, the DefaultRegistry is used.
+	Registry *Registry
+
+	// MaxLength is the maximum length of the expression in bytes.
+	MaxLength int
+	// MaxTokens is the maximum amount of tokens without whitespace.
+	// The scanning stops at the first token above the limit.
+	MaxTokens int
+	// MaxDepth is the maximum depth of the abstract syntax tree, which grows
+	// with nested parentheses and function calls as well as with long chains
+	// of operators.
+	MaxDepth int
+	// MaxSteps is the maximum amount of nodes of the abstract syntax tree
+	// which are evaluated.
+	MaxSteps int
+}
+
+// SolveContext solves a mathematical calculation like SolveWith, but stops
+// if ctx is done or if the expression exceeds the limits in opts.
+// This makes it safe to solve expressions from untrusted input.
+//
+// A done context results in an *EvalError with the kind Canceled which wraps
+// the error of the context. An exceeded limit results in a *LimitError with
+// the kind InputTooLong, TooManyTokens, TooDeep or TooManySteps.
+func SolveContext(ctx context.Context, s string, opts SolveOptions) (float64, error) {
+	if opts.MaxLength > 0 && len(s) > opts.MaxLength {
+		return 0, &LimitError{Kind: InputTooLong, Limit: opts.MaxLength, Msg: fmt.Sprintf("expression is longer than %d bytes", opts.MaxLength)}
+	}
+	if err := contextError(ctx); err != nil {
+		return 0, err
+	}
+
+	p := NewParserOptions(strings.NewReader(s), opts.Parse)
+	p.registry = opts.Registry
+	if opts.MaxTokens > 0 {
+		p.s = &tokenLimiter{s: p.s, max: opts.MaxTokens}
+	}
+	tokens, err := p.Parse()
 	if err != nil {
 		return 0, err
 	}
 
-	stack, err = ShuntingYard(stack)
+	postfix, err := ShuntingYard(tokens)
 	if err != nil {
 		return 0, err
 	}
+	depth, err := validatePostfix(postfix)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return 0, &LimitError{Kind: TooDeep, Limit: opts.MaxDepth, Msg: fmt.Sprintf("expression is nested deeper than %d levels", opts.MaxDepth)}
+	}
+
+	tree, err := buildTree(postfix)
+	if err != nil {
+		return 0, err
+	}
+	if err := contextError(ctx); err != nil {
+		return 0, err
+	}
 
-	return SolvePostfix(stack)
+	e := &evaluator{vars: opts.Vars, ctx: ctx, maxSteps: opts.MaxSteps, lenient: opts.Lenient, registry: opts.Registry}
+	return e.eval(tree)
 }
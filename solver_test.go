@@ -1,8 +1,13 @@
 package calc_test
 
 import (
-	"github.com/aligator/calc"
+	"context"
+	"errors"
+	"math"
+	"strings"
 	"testing"
+
+	"github.com/aligator/calc"
 )
 
 func TestContainsLetter(t *testing.T) {
@@ -44,19 +49,111 @@ func TestSolve(t *testing.T) {
 		{name: "with constant", input: "2^3+PI", want: 11.141592653589793},
 		{name: "with function", input: "COS(5)", want: 0.2836621854632263},
 		{name: "with function which itself contains also a calculation", input: "COS(3+2)", want: 0.2836621854632263},
+		{name: "with function and space before the parenthesis", input: "COS (0)", want: 1},
 		{name: "with parentheses", input: "2*(5+3)", want: 16},
 		{name: "with more parentheses", input: "((2*(5+3))+4)*(300/100)", want: 60},
 		{name: "with spaces, tabs and newlines", input: "    (  \n   (2*(  \t\t\t5+    3))+4)*  \n       (300    / 100)   ", want: 60},
 		{name: "invalid calculation: ends with operator", input: "((2*(5+3))+4)+", wantErr: true},
-		{name: "invalid calculation: double operator", input: "((2*(5+3))++4)", wantErr: true},
+		{name: "invalid calculation: double operator", input: "((2*(5+3))*/4)", wantErr: true},
+		{name: "unary plus after operator", input: "((2*(5+3))++4)", want: 20},
 		{name: "invalid calculation: wrong parentheses", input: "((2*(5+3)+4", wantErr: true},
 		{name: "invalid calculation: wrong parentheses2", input: "(2*(5+3))+4)", wantErr: true},
 		{name: "invalid calculation: invalid function", input: "(2*(5+3))+4*LOOL(5)", wantErr: true},
 		{name: "invalid calculation: invalid constant", input: "(2*(5+3))+4*LOOL", wantErr: true},
+		{name: "scientific notation", input: "1.5e-3*2E+3", want: 3},
+		{name: "scientific notation with separators", input: "1_000e1_0 / 1e13", want: 1},
+		{name: "leading dot", input: ".5+.25", want: 0.75},
+		{name: "invalid exponent", input: "1e+*2", wantErr: true},
 		{name: "invalid float", input: "2.243.4*345", wantErr: true},
 		{name: "invalid float2", input: "543*454.45.45.45", wantErr: true},
 		{name: "invalid float2 in function", input: "543*LOOL(5.345.54.35)", wantErr: true},
 		{name: "invalid calculation inside a function", input: "COS(3+)", wantErr: true},
+		{name: "function inside a function", input: "SQRT(ABS(-16))", want: 4},
+		{name: "negative function argument", input: "MAX(1, -2)", want: 1},
+		{name: "unary minus binds weaker than exp", input: "-2^2", want: -4},
+		{name: "unary minus in exponent", input: "2^-2", want: 0.25},
+		{name: "unary minus before multiplication", input: "-2*3", want: -6},
+		{name: "unary minus before constant", input: "-PI", want: -3.141592653589793},
+		{name: "unary minus before parentheses", input: "-(2+3)", want: -5},
+		{name: "unary minus before function", input: "-COS(0)", want: -1},
+		{name: "unary minus after operator", input: "2*-3", want: -6},
+		{name: "double unary minus", input: "--3", want: 3},
+		{name: "unary plus", input: "+3-+2", want: 1},
+		{name: "invalid calculation: unary multiplication", input: "*3", wantErr: true},
+		{name: "invalid calculation: only unary minus", input: "-", wantErr: true},
+		{name: "comparison true", input: "1+1 == 2", want: 1},
+		{name: "comparison false", input: "2 < 1", want: 0},
+		{name: "all comparisons", input: "(1 < 2) + (2 <= 2) + (3 > 2) + (2 >= 3) + (1 != 1)", want: 3},
+		{name: "comparison binds stronger than equality", input: "2 > 1 == 1", want: 1},
+		{name: "logical and", input: "1 < 2 && 2 < 3", want: 1},
+		{name: "logical or", input: "0 || 0", want: 0},
+		{name: "and binds stronger than or", input: "1 || 0 && 0", want: 1},
+		{name: "logical results are 0 or 1", input: "5 && 7", want: 1},
+		{name: "logical not", input: "!0 + !5", want: 1},
+		{name: "logical not binds stronger than plus", input: "!1 + 1", want: 1},
+		{name: "and short-circuits", input: "0 && 1/0", want: 0},
+		{name: "or short-circuits", input: "1 || 1/0", want: 1},
+		{name: "conditional", input: "2 > 1 ? 10 : 20", want: 10},
+		{name: "conditional else", input: "2 < 1 ? 10 : 20", want: 20},
+		{name: "conditional only evaluates one branch", input: "1 ? 2 : 1/0", want: 2},
+		{name: "nested conditional", input: "0 ? 1 : 0 ? 2 : 3", want: 3},
+		{name: "conditional inside then branch", input: "1 ? 0 ? 1 : 2 : 3", want: 2},
+		{name: "conditional binds weakest", input: "1 ? 2 : 3 + 1", want: 2},
+		{name: "conditional in parentheses", input: "(1 ? 2 : 3) + 1", want: 3},
+		{name: "conditional as function argument", input: "MAX(0 ? 5 : -5, -1 ? -2 : 2)", want: -2},
+		{name: "hex literal", input: "0x1F", want: 31},
+		{name: "upper case hex literal", input: "0XfF", want: 255},
+		{name: "binary literal", input: "0b1010", want: 10},
+		{name: "octal literal", input: "0o17", want: 15},
+		{name: "digit separators", input: "1_000_000 + 0.000_5", want: 1000000.0005},
+		{name: "hex digit separators", input: "0xFF_FF", want: 65535},
+		{name: "bitwise and", input: "0b1100 & 0b1010", want: 8},
+		{name: "bitwise or", input: "0b1100 | 0b1010", want: 14},
+		{name: "bitwise xor", input: "0b1100 xor 0b1010", want: 6},
+		{name: "bitwise not", input: "~0", want: -1},
+		{name: "shift left", input: "1 << 10", want: 1024},
+		{name: "shift right", input: "-5 >> 1", want: -3},
+		{name: "shift binds weaker than plus", input: "1 << 2 + 1", want: 8},
+		{name: "bitwise and binds stronger than comparison", input: "6 & 2 == 2", want: 1},
+		{name: "bitwise precedence", input: "1 | 6 xor 3 & 5", want: 7},
+		{name: "register mask", input: "(0xABCD >> 4) & 0xF", want: 12},
+		{name: "invalid calculation: bitwise and with fraction", input: "1 & 1.5", wantErr: true},
+		{name: "invalid calculation: bitwise not with fraction", input: "~0.5", wantErr: true},
+		{name: "largest shift", input: "1 << 52", want: 4503599627370496},
+		{name: "largest exact integer", input: "9007199254740991 & -1", want: 9007199254740991},
+		{name: "invalid calculation: shift out of the exact range", input: "1 << 2000", wantErr: true},
+		{name: "invalid calculation: bitwise and with rounded literal", input: "9007199254740993 & 1", wantErr: true},
+		{name: "invalid calculation: negative shift", input: "1 << -1", wantErr: true},
+		{name: "invalid calculation: empty hex literal", input: "0x", wantErr: true},
+		{name: "invalid calculation: invalid binary digit", input: "0b102", wantErr: true},
+		{name: "invalid calculation: double separator", input: "1__0", wantErr: true},
+		{name: "invalid calculation: trailing separator", input: "10_", wantErr: true},
+		{name: "invalid calculation: separator before point", input: "1_.5", wantErr: true},
+		{name: "invalid calculation: only not", input: "!", wantErr: true},
+		{name: "modulo", input: "7 % 3", want: 1},
+		{name: "modulo has the sign of the divisor", input: "-7 % 3", want: 2},
+		{name: "modulo of fractions", input: "5.5 % 2", want: 1.5},
+		{name: "floor division", input: "7 // 2", want: 3},
+		{name: "floor division rounds down", input: "-7 // 2", want: -4},
+		{name: "floor division and modulo match", input: "(-7 // 3) * 3 + -7 % 3", want: -7},
+		{name: "modulo binds like multiplication", input: "1 + 7 % 4 * 2", want: 7},
+		{name: "factorial", input: "5!", want: 120},
+		{name: "factorial of zero", input: "0!", want: 1},
+		{name: "factorial binds stronger than exp", input: "2^3!", want: 64},
+		{name: "factorial binds stronger than unary minus", input: "-3!", want: -6},
+		{name: "double factorial operator", input: "3!!", want: 720},
+		{name: "factorial of parentheses", input: "(1+2)! + 1", want: 7},
+		{name: "factorial and logical not", input: "!3! == 0", want: 1},
+		{name: "gamma for fractions", input: "ABS(0.5! - SQRT(PI)/2) < 0.000000000001", want: 1},
+		{name: "factorial function", input: "FACT(4)", want: 24},
+		{name: "invalid calculation: factorial of negative integer", input: "(-1)!", wantErr: true},
+		{name: "invalid calculation: modulo by zero", input: "1 % 0", wantErr: true},
+		{name: "invalid calculation: floor division by zero", input: "1 // 0", wantErr: true},
+		{name: "invalid calculation: conditional without colon", input: "1 ? 2", wantErr: true},
+		{name: "function calls with operators", input: "MAX(1, 2) * MIN(3, 4) + COS(0)", want: 7},
+		{name: "invalid calculation inside a nested function", input: "MAX(1, SQRT(4*))", wantErr: true},
+		{name: "comma outside of a function", input: "(1, 2)", wantErr: true},
+		{name: "unclosed function call", input: "MAX(1, 2", wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -72,6 +169,41 @@ func TestSolve(t *testing.T) {
 	}
 }
 
+func TestSolveWith(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		vars    calc.Env
+		want    float64
+		wantErr string
+	}{
+		{name: "without variables", input: "5+4", want: 9},
+		{name: "with variables", input: "x*y+1", vars: calc.Env{"X": 3, "Y": 4}, want: 13},
+		{name: "variable inside function", input: "SQRT(x)", vars: calc.Env{"X": 16}, want: 4},
+		{name: "negated variable", input: "2*-x", vars: calc.Env{"X": 3}, want: -6},
+		{name: "variable and constant", input: "2*r*PI", vars: calc.Env{"R": 0.5}, want: 3.141592653589793},
+		{name: "undefined variable", input: "x+1", wantErr: "1:1: undefined variable X"},
+		{name: "undefined variable between others", input: "x+y*z", vars: calc.Env{"X": 1, "Z": 2}, wantErr: "1:3: undefined variable Y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.SolveWith(tt.input, tt.vars)
+			if err != nil {
+				if err.Error() != tt.wantErr {
+					t.Errorf("SolveWith() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			} else if tt.wantErr != "" {
+				t.Errorf("SolveWith() got no error, want %v", tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SolveWith() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSolveFunction(t *testing.T) {
 	type args struct {
 		s string
@@ -82,7 +214,10 @@ func TestSolveFunction(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{name: "single argument", args: args{s: "SQRT(16)"}, want: "4"},
+		{name: "several arguments", args: args{s: "MAX(1, 3+4, 2)"}, want: "7"},
+		{name: "unknown function", args: args{s: "LOOL(5)"}, wantErr: true},
+		{name: "not a function", args: args{s: "LOOL"}, wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -99,6 +234,13 @@ func TestSolveFunction(t *testing.T) {
 }
 
 func TestSolvePostfix(t *testing.T) {
+	num := func(value string, column int) calc.Token {
+		return calc.Token{Type: calc.Number, Value: value, Pos: pos(column)}
+	}
+	op := func(value string, column int) calc.Token {
+		return calc.Token{Type: calc.Operator, Value: value, Pos: pos(column)}
+	}
+
 	type args struct {
 		tokens calc.Stack
 	}
@@ -106,15 +248,21 @@ func TestSolvePostfix(t *testing.T) {
 		name    string
 		args    args
 		want    float64
-		wantErr bool
+		wantErr string
 	}{
-		// TODO: Add test cases.
+		{name: "simple plus", args: args{tokens: calc.Stack{num("1", 1), num("2", 5), op("+", 3)}}, want: 3},
+		{name: "empty", args: args{tokens: calc.Stack{}}, wantErr: "empty expression - calculation could not be solved"},
+		{name: "missing operand", args: args{tokens: calc.Stack{num("2", 1), num("3", 5), op("*", 3), op("+", 7)}}, wantErr: "1:7: missing operand for '+'"},
+		{name: "leftover operand", args: args{tokens: calc.Stack{num("1", 1), num("3", 5), num("4", 9), op("+", 7)}}, wantErr: "1:5: unexpected value '3'"},
+		{name: "leftover operation", args: args{tokens: calc.Stack{num("1", 1), num("3", 5), num("4", 9), op("+", 7), op("-", 3), num("8", 11)}}, wantErr: "1:11: unexpected value '8'"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, err := calc.SolvePostfix(tt.args.tokens)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("SolvePostfix() error = %v, wantErr %v", err, tt.wantErr)
+			if err != nil || tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("SolvePostfix() error = %v, wantErr %v", err, tt.wantErr)
+				}
 				return
 			}
 			if got != tt.want {
@@ -123,3 +271,87 @@ func TestSolvePostfix(t *testing.T) {
 		})
 	}
 }
+
+func TestSolveContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	registry := calc.NewRegistry()
+	if err := registry.Register("DOUBLE", 1, 1, func(args ...float64) (float64, error) {
+		return 2 * args[0], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		input    string
+		opts     calc.SolveOptions
+		want     float64
+		wantKind calc.ErrorKind
+		wantPos  calc.Position
+	}{
+		{name: "without limits", input: "x*2", opts: calc.SolveOptions{Vars: calc.Env{"X": 3}}, want: 6},
+		{name: "with parse options", input: "2x", opts: calc.SolveOptions{Parse: calc.ParseOptions{ImplicitMul: true}, Vars: calc.Env{"X": 3}}, want: 6},
+		{name: "within all limits", input: "(1+2)*3", opts: calc.SolveOptions{MaxLength: 7, MaxTokens: 7, MaxDepth: 3, MaxSteps: 5}, want: 9},
+		{name: "input too long", input: "(1+2)*3", opts: calc.SolveOptions{MaxLength: 6}, wantKind: calc.InputTooLong},
+		{name: "too many tokens", input: "1 + 2 + 3", opts: calc.SolveOptions{MaxTokens: 4}, wantKind: calc.TooManyTokens, wantPos: pos(9)},
+		{name: "too many tokens before a syntax error", input: "1 + 2 + 3 + $", opts: calc.SolveOptions{MaxTokens: 4}, wantKind: calc.TooManyTokens, wantPos: pos(9)},
+		{name: "too many tokens before unclosed parenthesis", input: "(1 + 2 + 3", opts: calc.SolveOptions{MaxTokens: 4}, wantKind: calc.TooManyTokens, wantPos: pos(8)},
+		{name: "too deep", input: "((((1))))+2", opts: calc.SolveOptions{MaxDepth: 1}, wantKind: calc.TooDeep},
+		{name: "long operator chain too deep", input: strings.Repeat("1+", 100) + "1", opts: calc.SolveOptions{MaxDepth: 50}, wantKind: calc.TooDeep},
+		{name: "unary operators too deep", input: strings.Repeat("-", 100) + "1", opts: calc.SolveOptions{MaxDepth: 50}, wantKind: calc.TooDeep},
+		{name: "too many steps", input: "SUM(1, 2, 3)", opts: calc.SolveOptions{MaxSteps: 3}, wantKind: calc.TooManySteps},
+		{name: "skipped branches are no steps", input: "0 && 1+1+1 ? 1+1+1 : 2", opts: calc.SolveOptions{MaxSteps: 4}, want: 2},
+		{name: "canceled", ctx: canceled, input: "1+2", wantKind: calc.Canceled},
+		{name: "strict by default", input: "pj + 1", wantKind: calc.UnknownIdentifier},
+		{name: "lenient", input: "pj + 1", opts: calc.SolveOptions{Lenient: true}, want: 1},
+		{name: "lenient with known names", input: "x + pi", opts: calc.SolveOptions{Lenient: true, Vars: calc.Env{"X": 1}}, want: 1 + math.Pi},
+		{name: "lenient keeps unknown functions", input: "LOOL(1)", opts: calc.SolveOptions{Lenient: true}, wantKind: calc.UnknownFunction},
+		{name: "own registry", input: "DOUBLE(2)", opts: calc.SolveOptions{Registry: registry}, want: 4},
+		{name: "own registry without built-ins", input: "SQRT(4)", opts: calc.SolveOptions{Registry: registry}, wantKind: calc.UnknownFunction},
+		{name: "own registry with implicit multiplication", input: "2 DOUBLE (3)", opts: calc.SolveOptions{Parse: calc.ParseOptions{ImplicitMul: true}, Registry: registry}, want: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			got, err := calc.SolveContext(ctx, tt.input, tt.opts)
+			if tt.wantKind == 0 {
+				if err != nil {
+					t.Fatalf("SolveContext() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("SolveContext() got = %v, want %v", got, tt.want)
+				}
+				return
+			}
+
+			var limitErr *calc.LimitError
+			var evalErr *calc.EvalError
+			switch {
+			case errors.As(err, &limitErr):
+				if limitErr.Kind != tt.wantKind {
+					t.Errorf("SolveContext() error kind = %v, want %v", limitErr.Kind, tt.wantKind)
+				}
+				if limitErr.Pos != tt.wantPos {
+					t.Errorf("SolveContext() error position = %v, want %v", limitErr.Pos, tt.wantPos)
+				}
+			case errors.As(err, &evalErr) && evalErr.Kind == calc.Canceled:
+				if tt.wantKind != calc.Canceled || !errors.Is(err, context.Canceled) {
+					t.Errorf("SolveContext() error = %v, want %v", err, tt.wantKind)
+				}
+			case errors.As(err, &evalErr):
+				if evalErr.Kind != tt.wantKind {
+					t.Errorf("SolveContext() error kind = %v, want %v", evalErr.Kind, tt.wantKind)
+				}
+			default:
+				t.Errorf("SolveContext() error = %v, want %v", err, tt.wantKind)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package calc
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum amount of names listed by suggestion.
+const maxSuggestions = 3
+
+// suggestion returns a hint such as ", did you mean PI?" which lists the
+// candidates with the smallest edit distance to name.
+// It returns "" if no candidate is close enough to be a likely typo.
+func suggestion(name string, candidates ...[]string) string {
+	n := len([]rune(name))
+	// Short names are only one edit away from many other short names.
+	best := 1 + n/4
+	if best >= n {
+		best = n - 1
+	}
+
+	var matches []string
+	seen := map[string]bool{}
+	for _, names := range candidates {
+		for _, candidate := range names {
+			if seen[candidate] || candidate == name {
+				continue
+			}
+			seen[candidate] = true
+
+			d := levenshtein(name, candidate)
+			if d > best || d >= len([]rune(candidate)) {
+				continue
+			}
+			if d < best {
+				best, matches = d, nil
+			}
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	sort.Strings(matches)
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	if len(matches) == 1 {
+		return ", did you mean " + matches[0] + "?"
+	}
+	return ", did you mean " + strings.Join(matches[:len(matches)-1], ", ") + " or " + matches[len(matches)-1] + "?"
+}
+
+// caseSuggestion returns a hint such as ", did you mean x?" if one of the
+// candidates only differs in case from name, which usually means that the
+// Scanner upper-cased the identifier but the variable is not upper-case.
+func caseSuggestion(name string, candidates []string) string {
+	if name != strings.ToUpper(name) {
+		return ""
+	}
+
+	var match string
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, name) && (match == "" || candidate < match) {
+			match = candidate
+		}
+	}
+	if match == "" {
+		return ""
+	}
+	return ", did you mean " + match + "? Variables have to be upper-case unless ScanOptions.CaseSensitive is set"
+}
+
+// levenshtein returns the amount of single rune insertions, deletions and
+// substitutions needed to change a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// prev and cur are two rows of the distance matrix.
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
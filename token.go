@@ -12,6 +12,15 @@ type Token struct {
 	// Value of the Token.
 	// It should match the Type.
 	Value string
+
+	// Pos is the position of the first rune of the token.
+	Pos Position
+	// End is the position directly after the last rune of the token.
+	End Position
+
+	// Args is the amount of arguments of a Function token.
+	// It is only set in postfix notation by ShuntingYard.
+	Args int
 }
 
 // These constants are all possible TokenType values.
@@ -23,4 +32,11 @@ const (
 	Function
 	Operator
 	Whitespace
+	Comma
+	// UnaryOperator is a prefix operator with a single operand, such as the "-" in "-x".
+	UnaryOperator
+	// PostfixOperator is an operator after its single operand, such as the "!" in "x!".
+	PostfixOperator
+	// Semicolon separates the statements of a script.
+	Semicolon
 )